@@ -0,0 +1,370 @@
+package ast
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"text/template/parse"
+
+	tutil "github.com/hermit-ink/goldmark-template/util"
+)
+
+// ActionKind classifies a template action by its leading keyword.
+type ActionKind int
+
+const (
+	// ActionPipeline is a plain pipeline such as {{ .Title }} or
+	// {{ $x := .Title }}.
+	ActionPipeline ActionKind = iota
+	// ActionIf is {{ if pipeline }}.
+	ActionIf
+	// ActionElse is a bare {{ else }}.
+	ActionElse
+	// ActionElseIf is {{ else if pipeline }}.
+	ActionElseIf
+	// ActionElseWith is {{ else with pipeline }}.
+	ActionElseWith
+	// ActionRange is {{ range pipeline }}.
+	ActionRange
+	// ActionWith is {{ with pipeline }}.
+	ActionWith
+	// ActionEnd is {{ end }}.
+	ActionEnd
+	// ActionDefine is {{ define "name" }}.
+	ActionDefine
+	// ActionBlock is {{ block "name" pipeline }}.
+	ActionBlock
+	// ActionTemplate is {{ template "name" pipeline }}.
+	ActionTemplate
+	// ActionBreak is {{ break }}.
+	ActionBreak
+	// ActionContinue is {{ continue }}.
+	ActionContinue
+	// ActionComment is {{/* comment */}}.
+	ActionComment
+)
+
+var actionKindNames = [...]string{
+	ActionPipeline: "Pipeline",
+	ActionIf:       "If",
+	ActionElse:     "Else",
+	ActionElseIf:   "ElseIf",
+	ActionElseWith: "ElseWith",
+	ActionRange:    "Range",
+	ActionWith:     "With",
+	ActionEnd:      "End",
+	ActionDefine:   "Define",
+	ActionBlock:    "Block",
+	ActionTemplate: "Template",
+	ActionBreak:    "Break",
+	ActionContinue: "Continue",
+	ActionComment:  "Comment",
+}
+
+// String implements fmt.Stringer.
+func (k ActionKind) String() string {
+	if k < 0 || int(k) >= len(actionKindNames) {
+		return "Unknown"
+	}
+	return actionKindNames[k]
+}
+
+var actionKindKeywords = [...]string{
+	ActionPipeline: "pipeline",
+	ActionIf:       "if",
+	ActionElse:     "else",
+	ActionElseIf:   "else if",
+	ActionElseWith: "else with",
+	ActionRange:    "range",
+	ActionWith:     "with",
+	ActionEnd:      "end",
+	ActionDefine:   "define",
+	ActionBlock:    "block",
+	ActionTemplate: "template",
+	ActionBreak:    "break",
+	ActionContinue: "continue",
+	ActionComment:  "comment",
+}
+
+// Keyword returns the template keyword of the kind as it is written in an
+// action, such as "else if".
+func (k ActionKind) Keyword() string {
+	if k < 0 || int(k) >= len(actionKindKeywords) {
+		return "unknown"
+	}
+	return actionKindKeywords[k]
+}
+
+// OpensBlock reports whether actions of this kind must be closed by a
+// matching {{ end }}.
+func (k ActionKind) OpensBlock() bool {
+	switch k {
+	case ActionIf, ActionRange, ActionWith, ActionDefine, ActionBlock:
+		return true
+	}
+	return false
+}
+
+// IsBranch reports whether actions of this kind start another branch of an
+// enclosing if, with or range.
+func (k ActionKind) IsBranch() bool {
+	return k == ActionElse || k == ActionElseIf || k == ActionElseWith
+}
+
+// Action is the parsed form of a single template action.
+type Action struct {
+	// Kind is the kind of the action.
+	Kind ActionKind
+
+	// TrimLeft reports whether the action starts with a {{- trim marker.
+	TrimLeft bool
+
+	// TrimRight reports whether the action ends with a -}} trim marker.
+	TrimRight bool
+
+	// Name is the template name of define, block and template actions.
+	Name string
+
+	// Pipe is the pipeline of the action, or nil if the action has none.
+	Pipe *parse.PipeNode
+
+	// base is the position of the action in the source it was parsed
+	// from, which starts with synthesized declarations and wrappers.
+	base int
+}
+
+// Pos returns the position of n, a node of the action's pipeline, as a byte
+// offset into the action's content.
+func (a *Action) Pos(n parse.Node) int {
+	return int(n.Position()) - a.base
+}
+
+const actionParseName = "action"
+
+// ParseAction parses a single template action, delimiters included.
+//
+// The action is classified even if its pipeline is malformed, so callers
+// always get a usable Action; the returned error reports what
+// text/template/parse found wrong with it.
+func ParseAction(content []byte) (*Action, error) {
+	return ParseActionDelims(content, tutil.DefaultDelimiters)
+}
+
+// ParseActionDelims is like ParseAction for an action enclosed in the given
+// delimiters.
+func ParseActionDelims(content []byte, delims tutil.Delimiters) (*Action, error) {
+	a := &Action{}
+	if !bytes.HasPrefix(content, delims.Left) || !bytes.HasSuffix(content, delims.Right) ||
+		len(content) < len(delims.Left)+len(delims.Right) {
+		return a, errors.New("action is not enclosed in delimiters")
+	}
+	body := content[len(delims.Left) : len(content)-len(delims.Right)]
+	if len(body) >= 2 && body[0] == '-' && isTrimSpace(body[1]) {
+		a.TrimLeft = true
+		body = body[1:]
+	}
+	if len(body) >= 2 && body[len(body)-1] == '-' && isTrimSpace(body[len(body)-2]) {
+		a.TrimRight = true
+		body = body[:len(body)-1]
+	}
+	a.Kind = classifyAction(bytes.TrimSpace(body))
+
+	l, r := string(delims.Left), string(delims.Right)
+	// Variables are usually declared by other actions in the document, so
+	// declare every one this action mentions to parse it in isolation.
+	decls := actionVariables(body)
+	prefix := ""
+	for _, v := range decls {
+		prefix += l + v + " := 0" + r
+	}
+	src := string(content)
+	wrapper := ""
+	switch a.Kind {
+	case ActionIf, ActionRange, ActionWith, ActionDefine, ActionBlock:
+		src += l + "end" + r
+	case ActionElse, ActionElseIf, ActionEnd:
+		wrapper = l + "if 1" + r
+		if a.Kind != ActionEnd {
+			src += l + "end" + r
+		}
+	case ActionElseWith:
+		wrapper = l + "with 1" + r
+		src += l + "end" + r
+	case ActionBreak, ActionContinue:
+		wrapper = l + "range ." + r
+		src += l + "end" + r
+	}
+	src = wrapper + src
+	a.base = len(prefix) + len(wrapper)
+
+	t := parse.New(actionParseName)
+	t.Mode = parse.SkipFuncCheck | parse.ParseComments
+	treeSet := map[string]*parse.Tree{}
+	if _, err := t.Parse(prefix+src, l, r, treeSet); err != nil {
+		return a, parseError(err)
+	}
+
+	var node parse.Node
+	if len(t.Root.Nodes) > len(decls) {
+		node = t.Root.Nodes[len(decls)]
+	}
+	switch a.Kind {
+	case ActionElse, ActionElseIf, ActionElseWith:
+		if n, ok := node.(*parse.IfNode); ok && a.Kind != ActionElseWith {
+			node = elseBranch(n.ElseList)
+		} else if n, ok := node.(*parse.WithNode); ok {
+			node = elseBranch(n.ElseList)
+		}
+	case ActionEnd, ActionBreak, ActionContinue:
+		node = nil
+	case ActionDefine:
+		for name := range treeSet {
+			if name != actionParseName {
+				a.Name = name
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		a.Pipe = n.Pipe
+	case *parse.IfNode:
+		a.Pipe = n.Pipe
+	case *parse.RangeNode:
+		a.Pipe = n.Pipe
+	case *parse.WithNode:
+		a.Pipe = n.Pipe
+	case *parse.TemplateNode:
+		a.Name = n.Name
+		a.Pipe = n.Pipe
+	}
+	return a, nil
+}
+
+// Fields returns the field chains referenced by the action's pipeline, such
+// as ".User.Name" or "$.Site.URL", in the order they appear.
+func (a *Action) Fields() []string {
+	var fields []string
+	if a.Pipe != nil {
+		walkPipeFields(a.Pipe, func(n parse.Node) {
+			fields = append(fields, n.String())
+		})
+	}
+	return fields
+}
+
+func walkPipeFields(n parse.Node, fn func(parse.Node)) {
+	switch n := n.(type) {
+	case *parse.PipeNode:
+		for _, cmd := range n.Cmds {
+			walkPipeFields(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkPipeFields(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkPipeFields(n.Node, fn)
+	case *parse.FieldNode:
+		fn(n)
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 {
+			fn(n)
+		}
+	}
+}
+
+// actionVariables returns the distinct variable names, other than $, that
+// appear in body.
+func actionVariables(body []byte) []string {
+	var vars []string
+	seen := map[string]bool{}
+	for i := 0; i < len(body); i++ {
+		if body[i] != '$' {
+			continue
+		}
+		j := i + 1
+		for ; j < len(body) && isVariableChar(body[j]); j++ {
+		}
+		if j > i+1 && !seen[string(body[i:j])] {
+			seen[string(body[i:j])] = true
+			vars = append(vars, string(body[i:j]))
+		}
+		i = j - 1
+	}
+	return vars
+}
+
+func isVariableChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') || c >= 0x80
+}
+
+// parseError strips the "template: action:1:" prefix from an error reported
+// by text/template/parse, since the name and line refer to the synthesized
+// source rather than to the action.
+func parseError(err error) error {
+	msg := strings.TrimPrefix(err.Error(), "template: "+actionParseName+":")
+	if i := strings.Index(msg, ": "); i >= 0 && msg != err.Error() {
+		msg = msg[i+2:]
+	}
+	return errors.New(msg)
+}
+
+func elseBranch(list *parse.ListNode) parse.Node {
+	if list == nil || len(list.Nodes) == 0 {
+		return nil
+	}
+	return list.Nodes[0]
+}
+
+func classifyAction(body []byte) ActionKind {
+	if bytes.HasPrefix(body, []byte("/*")) {
+		return ActionComment
+	}
+	word, rest := nextWord(body)
+	switch string(word) {
+	case "if":
+		return ActionIf
+	case "else":
+		next, _ := nextWord(rest)
+		switch string(next) {
+		case "if":
+			return ActionElseIf
+		case "with":
+			return ActionElseWith
+		}
+		return ActionElse
+	case "range":
+		return ActionRange
+	case "with":
+		return ActionWith
+	case "end":
+		return ActionEnd
+	case "define":
+		return ActionDefine
+	case "block":
+		return ActionBlock
+	case "template":
+		return ActionTemplate
+	case "break":
+		return ActionBreak
+	case "continue":
+		return ActionContinue
+	}
+	return ActionPipeline
+}
+
+// nextWord returns the keyword-like word at the start of b and the rest of b
+// with leading spaces removed.
+func nextWord(b []byte) ([]byte, []byte) {
+	b = bytes.TrimLeft(b, " \t\r\n")
+	i := 0
+	for ; i < len(b) && (b[i] >= 'a' && b[i] <= 'z'); i++ {
+	}
+	return b[:i], bytes.TrimLeft(b[i:], " \t\r\n")
+}
+
+func isTrimSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
@@ -0,0 +1,135 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+
+	tutil "github.com/hermit-ink/goldmark-template/util"
+)
+
+func TestParseActionKinds(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		kind      ActionKind
+		tmplName  string
+		hasPipe   bool
+		trimLeft  bool
+		trimRight bool
+	}{
+		{name: "field", input: "{{ .Title }}", kind: ActionPipeline, hasPipe: true},
+		{name: "declaration", input: "{{ $x := .Title }}", kind: ActionPipeline, hasPipe: true},
+		{name: "if", input: "{{ if .Show }}", kind: ActionIf, hasPipe: true},
+		{name: "else", input: "{{ else }}", kind: ActionElse},
+		{name: "else if", input: "{{ else if .Other }}", kind: ActionElseIf, hasPipe: true},
+		{name: "else with", input: "{{ else with .Other }}", kind: ActionElseWith, hasPipe: true},
+		{name: "range", input: "{{ range $i, $e := .Items }}", kind: ActionRange, hasPipe: true},
+		{name: "with", input: "{{ with .User }}", kind: ActionWith, hasPipe: true},
+		{name: "end", input: "{{ end }}", kind: ActionEnd},
+		{name: "define", input: `{{ define "intro" }}`, kind: ActionDefine, tmplName: "intro"},
+		{name: "block", input: `{{ block "main" . }}`, kind: ActionBlock, tmplName: "main", hasPipe: true},
+		{name: "template", input: `{{ template "footer" .Site }}`, kind: ActionTemplate, tmplName: "footer", hasPipe: true},
+		{name: "template without pipeline", input: `{{ template "footer" }}`, kind: ActionTemplate, tmplName: "footer"},
+		{name: "break", input: "{{ break }}", kind: ActionBreak},
+		{name: "continue", input: "{{ continue }}", kind: ActionContinue},
+		{name: "comment", input: "{{/* note */}}", kind: ActionComment},
+		{name: "trimmed comment", input: "{{- /* note */ -}}", kind: ActionComment, trimLeft: true, trimRight: true},
+		{name: "trim left", input: "{{- .Title }}", kind: ActionPipeline, hasPipe: true, trimLeft: true},
+		{name: "trim right", input: "{{ .Title -}}", kind: ActionPipeline, hasPipe: true, trimRight: true},
+		{name: "negative number is not a trim marker", input: "{{-3}}", kind: ActionPipeline, hasPipe: true},
+		{name: "no spaces", input: "{{if .Show}}", kind: ActionIf, hasPipe: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAction([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ParseAction(%q) returned error: %v", tt.input, err)
+			}
+			if a.Kind != tt.kind {
+				t.Errorf("Kind: expected %v, got %v", tt.kind, a.Kind)
+			}
+			if a.Name != tt.tmplName {
+				t.Errorf("Name: expected %q, got %q", tt.tmplName, a.Name)
+			}
+			if (a.Pipe != nil) != tt.hasPipe {
+				t.Errorf("Pipe: expected present=%v, got %v", tt.hasPipe, a.Pipe)
+			}
+			if a.TrimLeft != tt.trimLeft || a.TrimRight != tt.trimRight {
+				t.Errorf("Trim markers: expected (%v, %v), got (%v, %v)", tt.trimLeft, tt.trimRight, a.TrimLeft, a.TrimRight)
+			}
+		})
+	}
+}
+
+func TestParseActionErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		kind  ActionKind
+	}{
+		{name: "unterminated string", input: `{{ printf "x }}`, kind: ActionPipeline},
+		{name: "if without pipeline", input: "{{ if }}", kind: ActionIf},
+		{name: "end with arguments", input: "{{ end .X }}", kind: ActionEnd},
+		{name: "nested delimiters", input: "{{ if {{ .X }} }}", kind: ActionIf},
+		{name: "missing delimiters", input: ".Title", kind: ActionPipeline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAction([]byte(tt.input))
+			if err == nil {
+				t.Fatalf("ParseAction(%q): expected an error", tt.input)
+			}
+			if a == nil || a.Kind != tt.kind {
+				t.Errorf("Kind: expected %v, got %+v", tt.kind, a)
+			}
+		})
+	}
+}
+
+func TestActionFields(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "{{ .User.Name }}", expected: []string{".User.Name"}},
+		{input: "{{ $.Site.URL }}", expected: []string{"$.Site.URL"}},
+		{input: `{{ index .Params "x" }}`, expected: []string{".Params"}},
+		{input: "{{ if and .A (eq .B.C $x.D) }}", expected: []string{".A", ".B.C", "$x.D"}},
+		{input: "{{ .Value | printf \"%s\" }}", expected: []string{".Value"}},
+		{input: "{{ range $i, $e := .Items }}", expected: []string{".Items"}},
+		{input: "{{ $x }}", expected: nil},
+		{input: "{{ end }}", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			a, err := ParseAction([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ParseAction(%q) returned error: %v", tt.input, err)
+			}
+			if got := a.Fields(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Fields(%q): expected %q, got %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseActionDelims(t *testing.T) {
+	delims := tutil.NewDelimiters("[[", "]]")
+	a, err := ParseActionDelims([]byte(`[[- if eq .Kind "}}" -]]`), delims)
+	if err != nil {
+		t.Fatalf("ParseActionDelims returned error: %v", err)
+	}
+	if a.Kind != ActionIf || !a.TrimLeft || !a.TrimRight {
+		t.Errorf("expected trimmed if action, got %+v", a)
+	}
+	if got := a.Fields(); !reflect.DeepEqual(got, []string{".Kind"}) {
+		t.Errorf("Fields: expected [.Kind], got %q", got)
+	}
+
+	if _, err := ParseActionDelims([]byte("{{ .Title }}"), delims); err == nil {
+		t.Errorf("expected an error for an action in the default delimiters")
+	}
+}
@@ -0,0 +1,97 @@
+package ast
+
+import (
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// PolicyRule is what happens to the actions in an ActionContext.
+type PolicyRule int
+
+const (
+	// PolicyAllow passes actions through for html/template to execute.
+	PolicyAllow PolicyRule = iota
+	// PolicyLiteral renders actions as string constants, so that
+	// html/template prints them as text instead of executing them.
+	PolicyLiteral
+	// PolicyDeny makes the conversion fail on actions.
+	PolicyDeny
+)
+
+var policyRuleNames = [...]string{
+	PolicyAllow:   "allow",
+	PolicyLiteral: "literal",
+	PolicyDeny:    "deny",
+}
+
+// String implements fmt.Stringer.
+func (r PolicyRule) String() string {
+	if r < 0 || int(r) >= len(policyRuleNames) {
+		return "unknown"
+	}
+	return policyRuleNames[r]
+}
+
+// ActionPolicy says what happens to the actions in each context, such as
+// allowing actions in text and link destinations but not in raw HTML or
+// code. Contexts it does not list are allowed.
+type ActionPolicy map[ActionContext]PolicyRule
+
+// Rule returns the rule for actions in context c.
+func (p ActionPolicy) Rule(c ActionContext) PolicyRule {
+	return p[c]
+}
+
+// Allows reports whether actions in context c are passed through.
+func (p ActionPolicy) Allows(c ActionContext) bool {
+	return p.Rule(c) == PolicyAllow
+}
+
+// FenceLanguage returns the language of the fenced code block n, the first
+// word of its info string. Unlike FencedCodeBlock.Language, it keeps an
+// action with spaces in it, such as {{ index .Langs 0 }}, whole.
+func FenceLanguage(n *gast.FencedCodeBlock, source []byte, delims tutil.Delimiters) []byte {
+	if n.Info == nil {
+		return nil
+	}
+	info := n.Info.Segment.Value(source)
+	i := 0
+	for i < len(info) && info[i] != ' ' {
+		if end := delims.FindActionEnd(info, i); end > 0 {
+			i = end
+			continue
+		}
+		i++
+	}
+	return info[:i]
+}
+
+// IsLiteralFence reports whether the actions in the fenced code block n are
+// shown as text rather than executed. A literal attribute decides: only
+// literal=true and literal="true" make n literal, and any other value, such
+// as literal=false, makes it not literal. Without the attribute, n is
+// literal if it is in one of languages. A literal fence is literal whatever
+// the policy for ContextCodeBlock is, PolicyDeny included.
+//
+// Attribute lists only take name=value pairs, so a bare {literal} is not
+// an attribute at all and leaves n to its language.
+func IsLiteralFence(n *gast.FencedCodeBlock, source []byte, delims tutil.Delimiters, languages []string) bool {
+	if v, ok := n.AttributeString("literal"); ok {
+		switch v := v.(type) {
+		case bool:
+			return v
+		case []byte:
+			return string(v) == "true"
+		case string:
+			return v == "true"
+		}
+		return false
+	}
+	language := string(FenceLanguage(n, source, delims))
+	for _, l := range languages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package ast
+
+import (
+	"strconv"
+
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Fields of the values that ValueSegments records. Attribute values are
+// recorded under AttributeField.
+const (
+	// FieldDestination is the destination of a link or image.
+	FieldDestination = "destination"
+	// FieldTitle is the title of a link or image.
+	FieldTitle = "title"
+	// FieldLabel is the label of an autolink.
+	FieldLabel = "label"
+)
+
+// AttributeField returns the field under which ValueSegments records the
+// value of the attribute name.
+func AttributeField(name []byte) string {
+	return "attribute " + string(name)
+}
+
+// ValueSegments records where in the source the values that parsers copy
+// into nodes, such as link destinations and titles and attribute values,
+// come from, so that the actions in them can be located. Only values that
+// are verbatim copies of their segment are recorded: a title with backslash
+// escapes or one that spans several lines is not.
+//
+// The parser collects the segments of a document in its parser.Context and
+// then appends them to the document as a ValueSegments node, which renders
+// nothing, so that they last as long as the document does without being
+// part of its attributes.
+type ValueSegments struct {
+	gast.BaseBlock
+	segments map[valueKey]text.Segment
+}
+
+type valueKey struct {
+	node  gast.Node
+	field string
+}
+
+// Dump implements Node.Dump.
+func (v *ValueSegments) Dump(source []byte, level int) {
+	gast.DumpHelper(v, source, level, map[string]string{
+		"Values": strconv.Itoa(len(v.segments)),
+	}, nil)
+}
+
+// KindValueSegments is a NodeKind of the ValueSegments node.
+var KindValueSegments = gast.NewNodeKind("ValueSegments")
+
+// Kind implements Node.Kind.
+func (v *ValueSegments) Kind() gast.NodeKind {
+	return KindValueSegments
+}
+
+// NewValueSegments returns a new, empty ValueSegments node.
+func NewValueSegments() *ValueSegments {
+	return &ValueSegments{segments: map[valueKey]text.Segment{}}
+}
+
+// ValueSegmentsOf returns the ValueSegments of the document n is part of,
+// or nil if there is none.
+func ValueSegmentsOf(n gast.Node) *ValueSegments {
+	for p := n; p != nil; p = p.Parent() {
+		if p.Kind() != gast.KindDocument {
+			continue
+		}
+		for c := p.LastChild(); c != nil; c = c.PreviousSibling() {
+			if v, ok := c.(*ValueSegments); ok {
+				return v
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// Set records that field of n was copied verbatim from segment s. An empty
+// segment removes what was recorded for field, for a value that is not a
+// verbatim copy. It does nothing on a nil ValueSegments.
+func (v *ValueSegments) Set(n gast.Node, field string, s text.Segment) {
+	if v == nil {
+		return
+	}
+	key := valueKey{node: n, field: field}
+	if s.IsEmpty() {
+		delete(v.segments, key)
+		return
+	}
+	v.segments[key] = s
+}
+
+// Get returns the segment recorded for field of n. It is safe to call on a
+// nil ValueSegments.
+func (v *ValueSegments) Get(n gast.Node, field string) (text.Segment, bool) {
+	if v == nil {
+		return text.Segment{}, false
+	}
+	s, ok := v.segments[valueKey{node: n, field: field}]
+	return s, ok
+}
+
+// Offset returns the start of the segment recorded for field of n, or -1.
+func (v *ValueSegments) Offset(n gast.Node, field string) int {
+	if s, ok := v.Get(n, field); ok {
+		return s.Start
+	}
+	return -1
+}
@@ -0,0 +1,351 @@
+package ast
+
+import (
+	"strconv"
+	"text/template/parse"
+
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// ReferenceKind classifies a Reference.
+type ReferenceKind int
+
+const (
+	// ReferenceField is a field chain such as .User.Name, $.Site.URL or
+	// $item.Title.
+	ReferenceField ReferenceKind = iota
+	// ReferenceDot is a bare dot, {{ . }}.
+	ReferenceDot
+	// ReferenceVariable is a bare variable such as $item.
+	ReferenceVariable
+	// ReferenceFunction is a function call such as index or printf.
+	ReferenceFunction
+)
+
+var referenceKindNames = [...]string{
+	ReferenceField:    "field",
+	ReferenceDot:      "dot",
+	ReferenceVariable: "variable",
+	ReferenceFunction: "function",
+}
+
+// String implements fmt.Stringer.
+func (k ReferenceKind) String() string {
+	if k < 0 || int(k) >= len(referenceKindNames) {
+		return "unknown"
+	}
+	return referenceKindNames[k]
+}
+
+// ElemSegment is the Path segment that stands for the elements of a value
+// that is ranged over.
+const ElemSegment = "[]"
+
+// Reference is a use of data or of a function by an action.
+type Reference struct {
+	// Kind is the kind of the reference.
+	Kind ReferenceKind
+
+	// Name is the reference as written, such as ".User.Name", "$x" or
+	// "index".
+	Name string
+
+	// Path is the field path the reference reads, resolved against the
+	// data passed to the template. Fields of values that are ranged over
+	// follow an ElemSegment, so .Title inside {{ range .Posts }} has the
+	// path [Posts [] Title]. Path is nil when it cannot be resolved, for
+	// example inside a {{ define }}, and for functions.
+	Path []string
+
+	// Fields is the part of Path the reference names itself, such as
+	// [User Name] for .User.Name or [Name] for $p.Name. What comes before
+	// it in Path is what dot or the variable refers to.
+	Fields []string
+
+	// Keys holds the constant keys of an index call applied to the
+	// reference, such as ["x"] for {{ index .Params "x" }}.
+	Keys []string
+
+	// Ranged reports whether the reference is the value of a range action.
+	Ranged bool
+
+	// Args is the number of arguments the reference is called with when it
+	// is the first word of a command, as in {{ .User.Greet "hi" }}, the
+	// piped value of {{ "hi" | .User.Greet }} included.
+	Args int
+
+	// Offset, Line and Column give the position of the reference in the
+	// Markdown source. Line and Column are 1-based and count bytes.
+	Offset int
+	Line   int
+	Column int
+
+	// Context is where the action ends up in the generated HTML.
+	Context ActionContext
+
+	// Attribute is the attribute name of a ContextAttribute reference.
+	Attribute string
+
+	// Content is the action the reference appears in, delimiters included.
+	Content []byte
+
+	// Action is the parsed form of Content.
+	Action *Action
+
+	// Node is the node the action was found in.
+	Node gast.Node
+}
+
+// References returns every field, variable and function reference made by
+// the actions in doc, in the order they appear in the generated HTML. It
+// follows the actions wherever WalkActions finds them, and tracks what dot
+// and variables refer to through with, range and block actions to resolve
+// each Path. Actions with syntax errors are skipped.
+func References(doc gast.Node, source []byte, delims tutil.Delimiters) []Reference {
+	r := &referenceCollector{source: source}
+	r.frames = []*scope{{dot: []string{}, dollar: []string{}, vars: map[string][]string{}}}
+	_ = WalkActions(doc, source, delims, func(site *ActionSite) error {
+		if site.Err == nil {
+			r.action(site)
+		}
+		return nil
+	})
+	return r.refs
+}
+
+// scope is what dot and the variables refer to inside a block action.
+type scope struct {
+	dot    []string
+	dollar []string
+	vars   map[string][]string
+}
+
+type referenceCollector struct {
+	source []byte
+	frames []*scope
+	refs   []Reference
+	site   *ActionSite
+}
+
+func (r *referenceCollector) top() *scope {
+	return r.frames[len(r.frames)-1]
+}
+
+func (r *referenceCollector) action(site *ActionSite) {
+	r.site = site
+	a := site.Action
+	switch a.Kind {
+	case ActionEnd:
+		if len(r.frames) > 1 {
+			r.frames = r.frames[:len(r.frames)-1]
+		}
+		return
+	case ActionElse, ActionElseIf, ActionElseWith:
+		if len(r.frames) > 1 {
+			// Dot goes back to what it is around the block, or to the
+			// value of an else with.
+			frame := r.top()
+			frame.dot = r.frames[len(r.frames)-2].dot
+			value := r.pipe(a.Pipe, false)
+			if a.Kind == ActionElseWith {
+				frame.dot = value
+			}
+		}
+		return
+	}
+
+	if !a.Kind.OpensBlock() {
+		if a.Pipe != nil {
+			r.pipe(a.Pipe, false)
+		}
+		return
+	}
+
+	outer := r.top()
+	frame := &scope{dot: outer.dot, dollar: outer.dollar, vars: copyVars(outer.vars)}
+	r.frames = append(r.frames, frame)
+	switch a.Kind {
+	case ActionDefine:
+		frame.dot, frame.dollar, frame.vars = nil, nil, map[string][]string{}
+	case ActionBlock:
+		value := r.pipe(a.Pipe, false)
+		frame.dot, frame.dollar, frame.vars = value, value, map[string][]string{}
+	case ActionWith:
+		frame.dot = r.pipe(a.Pipe, false)
+	case ActionRange:
+		frame.dot = elem(r.pipe(a.Pipe, true))
+	case ActionIf:
+		r.pipe(a.Pipe, false)
+	}
+}
+
+// pipe records the references in p and returns the path of its value.
+// Declarations in p are made in the innermost scope.
+func (r *referenceCollector) pipe(p *parse.PipeNode, ranged bool) []string {
+	if p == nil {
+		return nil
+	}
+	var value []string
+	for i, cmd := range p.Cmds {
+		last := i == len(p.Cmds)-1
+		path := r.command(cmd, i > 0, ranged && last && len(cmd.Args) == 1)
+		if last && len(cmd.Args) == 1 {
+			value = path
+		}
+	}
+
+	frame := r.top()
+	switch {
+	case ranged && len(p.Decl) == 2:
+		frame.vars[p.Decl[0].Ident[0]] = nil
+		frame.vars[p.Decl[1].Ident[0]] = elem(value)
+	case ranged && len(p.Decl) == 1:
+		frame.vars[p.Decl[0].Ident[0]] = elem(value)
+	case len(p.Decl) == 1:
+		frame.vars[p.Decl[0].Ident[0]] = value
+	}
+	return value
+}
+
+// command records the references in cmd and returns the path of its value
+// when it is a single operand. A piped command receives one more argument.
+func (r *referenceCollector) command(cmd *parse.CommandNode, piped, ranged bool) []string {
+	if len(cmd.Args) == 0 {
+		return nil
+	}
+	args := len(cmd.Args) - 1
+	if piped {
+		args++
+	}
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok && id.Ident == "index" && len(cmd.Args) > 1 {
+		r.add(id, Reference{Kind: ReferenceFunction, Name: id.Ident, Args: args})
+		var keys []string
+		for _, arg := range cmd.Args[2:] {
+			key, ok := constantKey(arg)
+			if !ok {
+				keys = nil
+				break
+			}
+			keys = append(keys, key)
+		}
+		r.operand(cmd.Args[1], keys, false, 0)
+		for _, arg := range cmd.Args[2:] {
+			r.operand(arg, nil, false, 0)
+		}
+		return nil
+	}
+	value := r.operand(cmd.Args[0], nil, ranged, args)
+	for _, arg := range cmd.Args[1:] {
+		r.operand(arg, nil, false, 0)
+	}
+	if len(cmd.Args) != 1 {
+		return nil
+	}
+	return value
+}
+
+// operand records the references in n and returns the path it reads. args
+// is the number of arguments n is called with.
+func (r *referenceCollector) operand(n parse.Node, keys []string, ranged bool, args int) []string {
+	frame := r.top()
+	ref := Reference{Keys: keys, Ranged: ranged, Args: args}
+	switch n := n.(type) {
+	case *parse.FieldNode:
+		ref.Kind, ref.Name, ref.Fields = ReferenceField, n.String(), n.Ident
+		ref.Path = join(frame.dot, n.Ident)
+		r.add(n, ref)
+		return ref.Path
+	case *parse.VariableNode:
+		base := frame.vars[n.Ident[0]]
+		if n.Ident[0] == "$" {
+			base = frame.dollar
+		}
+		ref.Name, ref.Fields = n.String(), n.Ident[1:]
+		ref.Path = join(base, ref.Fields)
+		if len(n.Ident) == 1 {
+			if n.Ident[0] != "$" {
+				ref.Kind = ReferenceVariable
+				r.add(n, ref)
+			}
+		} else {
+			ref.Kind = ReferenceField
+			r.add(n, ref)
+		}
+		return ref.Path
+	case *parse.DotNode:
+		ref.Kind, ref.Name, ref.Path = ReferenceDot, ".", frame.dot
+		r.add(n, ref)
+		return frame.dot
+	case *parse.ChainNode:
+		ref.Kind, ref.Name, ref.Fields = ReferenceField, n.String(), n.Field
+		ref.Path = join(r.operand(n.Node, nil, false, 0), n.Field)
+		r.add(n, ref)
+		return ref.Path
+	case *parse.IdentifierNode:
+		r.add(n, Reference{Kind: ReferenceFunction, Name: n.Ident, Args: args})
+	case *parse.PipeNode:
+		return r.pipe(n, false)
+	}
+	return nil
+}
+
+// add records ref, made by n, with its position and context.
+func (r *referenceCollector) add(n parse.Node, ref Reference) {
+	site := r.site
+	offset := site.Offset
+	// Offsets inside the action only hold when the action itself is in the
+	// source; values copied out of it fall back to the enclosing node.
+	if pos := site.Action.Pos(n); pos >= 0 && pos < len(site.Content) &&
+		offset+len(site.Content) <= len(r.source) &&
+		string(r.source[offset:offset+len(site.Content)]) == string(site.Content) {
+		// Fields and variables are positioned at their last element;
+		// report where the whole chain starts.
+		for pos > 0 && (isVariableChar(site.Content[pos-1]) || site.Content[pos-1] == '.' || site.Content[pos-1] == '$') {
+			pos--
+		}
+		offset += pos
+	}
+	ref.Offset = offset
+	ref.Line, ref.Column = tutil.LineColumn(r.source, offset)
+	ref.Context = site.Context
+	ref.Attribute = site.Attribute
+	ref.Content = site.Content
+	ref.Action = site.Action
+	ref.Node = site.Node
+	r.refs = append(r.refs, ref)
+}
+
+func constantKey(n parse.Node) (string, bool) {
+	switch n := n.(type) {
+	case *parse.StringNode:
+		return n.Text, true
+	case *parse.NumberNode:
+		if n.IsInt {
+			return strconv.FormatInt(n.Int64, 10), true
+		}
+	}
+	return "", false
+}
+
+// join returns base followed by fields, or nil if base is unknown.
+func join(base []string, fields []string) []string {
+	if base == nil {
+		return nil
+	}
+	path := make([]string, 0, len(base)+len(fields))
+	path = append(path, base...)
+	return append(path, fields...)
+}
+
+func elem(path []string) []string {
+	return join(path, []string{ElemSegment})
+}
+
+func copyVars(vars map[string][]string) map[string][]string {
+	c := make(map[string][]string, len(vars))
+	for k, v := range vars {
+		c[k] = v
+	}
+	return c
+}
@@ -1,6 +1,7 @@
 package ast
 
 import (
+	tutil "github.com/hermit-ink/goldmark-template/util"
 	gast "github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/text"
 )
@@ -10,11 +11,25 @@ type TemplateAction struct {
 	gast.BaseInline
 	Segment text.Segment
 	Content []byte
+
+	// Action is the parsed form of Content. It is never nil; when Content
+	// could not be parsed the kind is still classified and Err is set.
+	Action *Action
+
+	// Err is the error text/template/parse reported for Content, if any.
+	Err error
 }
 
 // Dump implements Node.Dump.
 func (n *TemplateAction) Dump(source []byte, level int) {
-	gast.DumpHelper(n, source, level, nil, nil)
+	m := map[string]string{
+		"Content": string(n.Content),
+		"Kind":    n.Action.Kind.String(),
+	}
+	if n.Err != nil {
+		m["Err"] = n.Err.Error()
+	}
+	gast.DumpHelper(n, source, level, m, nil)
 }
 
 // KindTemplateAction is a NodeKind of the TemplateAction node.
@@ -27,8 +42,17 @@ func (n *TemplateAction) Kind() gast.NodeKind {
 
 // NewTemplateAction returns a new TemplateAction node.
 func NewTemplateAction(content []byte, segment text.Segment) *TemplateAction {
+	return NewTemplateActionDelims(content, segment, tutil.DefaultDelimiters)
+}
+
+// NewTemplateActionDelims returns a new TemplateAction node for an action
+// enclosed in the given delimiters.
+func NewTemplateActionDelims(content []byte, segment text.Segment, delims tutil.Delimiters) *TemplateAction {
+	action, err := ParseActionDelims(content, delims)
 	return &TemplateAction{
 		Content: content,
 		Segment: segment,
+		Action:  action,
+		Err:     err,
 	}
 }
@@ -0,0 +1,84 @@
+package ast
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// TemplateBlock represents an if, range, with, define or block action that
+// stands alone on a line. Its children are the Markdown blocks enclosed
+// between the opening action and the matching {{ end }}.
+type TemplateBlock struct {
+	gast.BaseBlock
+
+	// Opener is the action that opened the block.
+	Opener *TemplateAction
+
+	// Closer is the {{ end }} that closed the block, or nil if the document
+	// or the container of the block ended first.
+	Closer *TemplateAction
+}
+
+// Dump implements Node.Dump.
+func (n *TemplateBlock) Dump(source []byte, level int) {
+	m := map[string]string{
+		"Opener": string(n.Opener.Content),
+	}
+	if n.Closer != nil {
+		m["Closer"] = string(n.Closer.Content)
+	}
+	gast.DumpHelper(n, source, level, m, nil)
+}
+
+// KindTemplateBlock is a NodeKind of the TemplateBlock node.
+var KindTemplateBlock = gast.NewNodeKind("TemplateBlock")
+
+// Kind implements Node.Kind.
+func (n *TemplateBlock) Kind() gast.NodeKind {
+	return KindTemplateBlock
+}
+
+// NewTemplateBlock returns a new TemplateBlock node opened by the given
+// action.
+func NewTemplateBlock(opener *TemplateAction) *TemplateBlock {
+	return &TemplateBlock{
+		Opener: opener,
+	}
+}
+
+// TemplateActionBlock represents an action-only line that does not open a
+// block of its own, such as an {{ else }} between the branches of a
+// TemplateBlock, an {{ end }} whose opener is not block-level or a
+// {{ template }} call.
+type TemplateActionBlock struct {
+	gast.BaseBlock
+
+	// Action is the action on the line.
+	Action *TemplateAction
+}
+
+// IsRaw implements Node.IsRaw.
+func (n *TemplateActionBlock) IsRaw() bool {
+	return true
+}
+
+// Dump implements Node.Dump.
+func (n *TemplateActionBlock) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, map[string]string{
+		"Action": string(n.Action.Content),
+	}, nil)
+}
+
+// KindTemplateActionBlock is a NodeKind of the TemplateActionBlock node.
+var KindTemplateActionBlock = gast.NewNodeKind("TemplateActionBlock")
+
+// Kind implements Node.Kind.
+func (n *TemplateActionBlock) Kind() gast.NodeKind {
+	return KindTemplateActionBlock
+}
+
+// NewTemplateActionBlock returns a new TemplateActionBlock node.
+func NewTemplateActionBlock(action *TemplateAction) *TemplateActionBlock {
+	return &TemplateActionBlock{
+		Action: action,
+	}
+}
@@ -0,0 +1,37 @@
+package ast
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// TemplateEscape represents an escaped left delimiter, such as \{{, that
+// stands for the delimiter itself rather than the start of an action.
+type TemplateEscape struct {
+	gast.BaseInline
+
+	// Segment is the position of the escape sequence in the source.
+	Segment text.Segment
+}
+
+// Dump implements Node.Dump.
+func (n *TemplateEscape) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, map[string]string{
+		"Escape": string(n.Segment.Value(source)),
+	}, nil)
+}
+
+// KindTemplateEscape is a NodeKind of the TemplateEscape node.
+var KindTemplateEscape = gast.NewNodeKind("TemplateEscape")
+
+// Kind implements Node.Kind.
+func (n *TemplateEscape) Kind() gast.NodeKind {
+	return KindTemplateEscape
+}
+
+// NewTemplateEscape returns a new TemplateEscape node.
+func NewTemplateEscape(segment text.Segment) *TemplateEscape {
+	return &TemplateEscape{
+		Segment: segment,
+	}
+}
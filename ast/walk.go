@@ -0,0 +1,329 @@
+package ast
+
+import (
+	"bytes"
+	"errors"
+
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// ActionContext describes where in the generated HTML an action ends up.
+type ActionContext int
+
+const (
+	// ContextText is an action in running text, including link text.
+	ContextText ActionContext = iota
+	// ContextBlock is an action that stands alone on a line.
+	ContextBlock
+	// ContextHref is an action in a link destination.
+	ContextHref
+	// ContextSrc is an action in an image source.
+	ContextSrc
+	// ContextTitle is an action in a link or image title.
+	ContextTitle
+	// ContextAlt is an action in image alt text.
+	ContextAlt
+	// ContextAttribute is an action in an attribute value.
+	ContextAttribute
+	// ContextCodeSpan is an action in a code span.
+	ContextCodeSpan
+	// ContextCodeBlock is an action in an indented or fenced code block.
+	ContextCodeBlock
+	// ContextRawHTML is an action in raw HTML.
+	ContextRawHTML
+)
+
+var actionContextNames = [...]string{
+	ContextText:      "text",
+	ContextBlock:     "block",
+	ContextHref:      "href",
+	ContextSrc:       "src",
+	ContextTitle:     "title",
+	ContextAlt:       "alt",
+	ContextAttribute: "attribute",
+	ContextCodeSpan:  "code span",
+	ContextCodeBlock: "code block",
+	ContextRawHTML:   "raw HTML",
+}
+
+// String implements fmt.Stringer.
+func (c ActionContext) String() string {
+	if c < 0 || int(c) >= len(actionContextNames) {
+		return "unknown"
+	}
+	return actionContextNames[c]
+}
+
+// ErrUnclosedAction is reported for a left delimiter that has no matching
+// right delimiter on the same line.
+var ErrUnclosedAction = errors.New("unclosed action")
+
+// ActionSite is an action found in a document by WalkActions.
+type ActionSite struct {
+	// Content is the action, delimiters included. For an unclosed action it
+	// is the rest of the line.
+	Content []byte
+
+	// Action is the parsed form of Content.
+	Action *Action
+
+	// Err is the error found in Content, if any.
+	Err error
+
+	// Offset is the position of Content in the source. Values that are not
+	// verbatim copies of the source, such as titles with backslash escapes,
+	// fall back to the position of the enclosing node.
+	Offset int
+
+	// Context is where the action ends up in the generated HTML.
+	Context ActionContext
+
+	// Attribute is the attribute name of a ContextAttribute action. It is
+	// empty for an action that stands for whole attributes, such as
+	// { {{ .Attrs }} }.
+	Attribute string
+
+	// Node is the node the action was found in.
+	Node gast.Node
+}
+
+// WalkActions calls fn for every action in doc in the order they appear in
+// the generated HTML, including actions that the Writer passes through in
+// destinations, attributes, code and raw HTML. Walking stops at the first
+// error returned by fn.
+func WalkActions(doc gast.Node, source []byte, delims tutil.Delimiters, fn func(*ActionSite) error) error {
+	w := &actionWalker{source: source, delims: delims, values: ValueSegmentsOf(doc), fn: fn}
+	return gast.Walk(doc, w.walk)
+}
+
+type actionWalker struct {
+	source []byte
+	delims tutil.Delimiters
+	values *ValueSegments
+	fn     func(*ActionSite) error
+}
+
+func (w *actionWalker) walk(n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if !entering {
+		var err error
+		switch n := n.(type) {
+		case *TemplateBlock:
+			if n.Closer != nil {
+				err = w.action(n.Closer, ContextBlock, n)
+			}
+		case *gast.Image:
+			err = w.value(LinkTitle(n, n.Title), FieldTitle, ContextTitle, "", n)
+		}
+		return gast.WalkContinue, err
+	}
+
+	if err := w.attributes(n); err != nil {
+		return gast.WalkStop, err
+	}
+
+	status := gast.WalkContinue
+	var err error
+	switch n := n.(type) {
+	case *TemplateAction:
+		context := ContextText
+		if inImage(n) {
+			context = ContextAlt
+		}
+		err = w.action(n, context, n)
+	case *TemplateBlock:
+		err = w.action(n.Opener, ContextBlock, n)
+	case *TemplateActionBlock:
+		err = w.action(n.Action, ContextBlock, n)
+	case *gast.Text:
+		context := ContextText
+		if inImage(n) {
+			context = ContextAlt
+		}
+		if prev, ok := n.PreviousSibling().(*gast.Text); ok && prev.Segment.Stop == n.Segment.Start {
+			break
+		}
+		// Inline parsers that decline a delimiter split the text around
+		// it, so scan each run of adjacent text as a whole.
+		stop := n.Segment.Stop
+		for next, ok := n.NextSibling().(*gast.Text); ok && next.Segment.Start == stop; next, ok = next.NextSibling().(*gast.Text) {
+			stop = next.Segment.Stop
+		}
+		err = w.scan(w.source[n.Segment.Start:stop], n.Segment.Start, context, "", n)
+	case *gast.Link:
+		if err = w.value(n.Destination, FieldDestination, ContextHref, "", n); err == nil {
+			err = w.value(LinkTitle(n, n.Title), FieldTitle, ContextTitle, "", n)
+		}
+	case *gast.Image:
+		err = w.value(n.Destination, FieldDestination, ContextSrc, "", n)
+	case *gast.AutoLink:
+		label := n.Label(w.source)
+		if err = w.value(label, FieldLabel, ContextHref, "", n); err == nil {
+			err = w.value(label, FieldLabel, ContextText, "", n)
+		}
+	case *gast.CodeSpan:
+		for c := n.FirstChild(); c != nil && err == nil; c = c.NextSibling() {
+			if t, ok := c.(*gast.Text); ok {
+				err = w.scan(t.Segment.Value(w.source), t.Segment.Start, ContextCodeSpan, "", n)
+			}
+		}
+		status = gast.WalkSkipChildren
+	case *gast.FencedCodeBlock:
+		if n.Info != nil {
+			err = w.scan(n.Info.Segment.Value(w.source), n.Info.Segment.Start, ContextAttribute, "class", n)
+		}
+		if err == nil {
+			err = w.lines(n, ContextCodeBlock)
+		}
+	case *gast.CodeBlock:
+		err = w.lines(n, ContextCodeBlock)
+	case *gast.HTMLBlock:
+		if err = w.lines(n, ContextRawHTML); err == nil && n.HasClosure() {
+			err = w.scan(n.ClosureLine.Value(w.source), n.ClosureLine.Start, ContextRawHTML, "", n)
+		}
+	case *gast.RawHTML:
+		for i := 0; i < n.Segments.Len() && err == nil; i++ {
+			s := n.Segments.At(i)
+			err = w.scan(s.Value(w.source), s.Start, ContextRawHTML, "", n)
+		}
+	}
+	if err != nil {
+		return gast.WalkStop, err
+	}
+	return status, nil
+}
+
+func (w *actionWalker) action(a *TemplateAction, context ActionContext, n gast.Node) error {
+	return w.fn(&ActionSite{
+		Content: a.Content,
+		Action:  a.Action,
+		Err:     a.Err,
+		Offset:  a.Segment.Start,
+		Context: context,
+		Node:    n,
+	})
+}
+
+func (w *actionWalker) attributes(n gast.Node) error {
+	for _, attr := range n.Attributes() {
+		value, ok := attr.Value.([]byte)
+		if !ok {
+			continue
+		}
+		name := string(attr.Name)
+		if bytes.HasPrefix(attr.Name, w.delims.Left) {
+			// An action that stands for whole attributes.
+			name = ""
+		}
+		if err := w.value(value, AttributeField(attr.Name), ContextAttribute, name, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *actionWalker) lines(n gast.Node, context ActionContext) error {
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		s := lines.At(i)
+		if err := w.scan(s.Value(w.source), s.Start, context, "", n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value scans the value of field of n, at the position recorded for it in
+// the value segments of the document.
+func (w *actionWalker) value(b []byte, field string, context ActionContext, attribute string, n gast.Node) error {
+	if len(b) == 0 {
+		return nil
+	}
+	offset := w.values.Offset(n, field)
+	if offset < 0 {
+		offset = nodeOffset(n)
+		// Without a position inside the value every action in it is
+		// reported at the node, so keep scan from adding its own offsets.
+		return w.scanAt(b, func(int) int { return offset }, context, attribute, n)
+	}
+	return w.scan(b, offset, context, attribute, n)
+}
+
+func (w *actionWalker) scan(b []byte, offset int, context ActionContext, attribute string, n gast.Node) error {
+	return w.scanAt(b, func(i int) int { return offset + i }, context, attribute, n)
+}
+
+func (w *actionWalker) scanAt(b []byte, position func(int) int, context ActionContext, attribute string, n gast.Node) error {
+	for i := 0; i < len(b); {
+		j := bytes.Index(b[i:], w.delims.Left)
+		if j < 0 {
+			return nil
+		}
+		start := i + j
+		site := &ActionSite{
+			Offset:    position(start),
+			Context:   context,
+			Attribute: attribute,
+			Node:      n,
+		}
+		end := w.delims.FindActionEnd(b, start)
+		if end < 0 {
+			stop := bytes.IndexByte(b[start:], '\n')
+			if stop < 0 {
+				stop = len(b) - start
+			}
+			site.Content = bytes.TrimRight(b[start:start+stop], "\r")
+			site.Action = &Action{}
+			site.Err = ErrUnclosedAction
+			i = start + len(w.delims.Left)
+		} else {
+			site.Content = b[start:end]
+			site.Action, site.Err = ParseActionDelims(site.Content, w.delims)
+			i = end
+		}
+		if err := w.fn(site); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LinkTitle returns title, the title of the link or image n, or nil if the
+// attribute list of n has a title attribute, which replaces it.
+func LinkTitle(n gast.Node, title []byte) []byte {
+	if _, ok := n.AttributeString("title"); ok {
+		return nil
+	}
+	return title
+}
+
+func inImage(n gast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == gast.KindImage {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeOffset returns the best known source position of n: its first text,
+// or the first line of the nearest block around it.
+func nodeOffset(n gast.Node) int {
+	for c := n.FirstChild(); c != nil; c = c.FirstChild() {
+		if t, ok := c.(*gast.Text); ok {
+			return t.Segment.Start
+		}
+	}
+	for p := n; p != nil; p = p.Parent() {
+		switch p := p.(type) {
+		case *TemplateBlock:
+			return p.Opener.Segment.Start
+		case *TemplateActionBlock:
+			return p.Action.Segment.Start
+		}
+		if p.Type() == gast.TypeBlock && p.Lines().Len() > 0 {
+			return p.Lines().At(0).Start
+		}
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package goldmarktemplate
 
 import (
 	"bytes"
+	"html/template"
 	"strings"
 	"testing"
 
@@ -130,3 +131,95 @@ func TestComplexAttributeScenarios(t *testing.T) {
 	}
 }
 
+
+func TestActionAttributeForms(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		input    string
+		expected string
+		executed string
+	}{
+		{
+			name:     "id shorthand",
+			input:    "# Title {#{{ .ID }}}",
+			expected: `<h1 id="{{ .ID }}">Title</h1>`,
+			executed: `<h1 id="intro">Title</h1>`,
+		},
+		{
+			name:     "class shorthand",
+			input:    "# Title {.{{ .Class }} .btn-{{ .Kind }}}",
+			expected: `<h1 class="{{ .Class }} btn-{{ .Kind }}">Title</h1>`,
+			executed: `<h1 class="wide btn-primary">Title</h1>`,
+		},
+		{
+			name:     "unquoted values",
+			input:    "# Title {id={{ .ID }} data-kind=k-{{ .Kind }}}",
+			expected: `<h1 id="{{ .ID }}" data-kind="k-{{ .Kind }}">Title</h1>`,
+			executed: `<h1 id="intro" data-kind="k-primary">Title</h1>`,
+		},
+		{
+			name:     "bare action",
+			input:    "# Title { {{ if .Hidden }}hidden{{ end }} }",
+			expected: `<h1 {{ if .Hidden }}hidden{{ end }}>Title</h1>`,
+			executed: `<h1 hidden>Title</h1>`,
+		},
+		{
+			name:     "bare action between attributes",
+			input:    "Title {.a {{ with .Kind }}data-kind=\"{{ . }}\"{{ end }} #x}\n---",
+			expected: `<h2 class="a" {{ with .Kind }}data-kind="{{ . }}"{{ end }} id="x">Title</h2>`,
+			executed: `<h2 class="a" data-kind="primary" id="x">Title</h2>`,
+		},
+		{
+			name:     "unbalanced bare action",
+			input:    "# Title { {{ if .Hidden }}hidden }",
+			expected: `<h1>Title { {{ if .Hidden }}hidden }</h1>`,
+		},
+		{
+			name:     "bare action closed after the list",
+			input:    "# Title { {{ if .Hidden }} } text {{ end }} }",
+			expected: `<h1>Title { {{ if .Hidden }} } text {{ end }} }</h1>`,
+		},
+		{
+			name:     "custom delimiters",
+			opts:     []Option{WithDelims("[[", "]]")},
+			input:    "# Title {#[[ .ID ]] [[ if .Hidden ]]hidden[[ end ]]}",
+			expected: `<h1 id="[[ .ID ]]" [[ if .Hidden ]]hidden[[ end ]]>Title</h1>`,
+		},
+	}
+
+	data := map[string]any{"ID": "intro", "Class": "wide", "Kind": "primary", "Hidden": true}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{ParserOptions(parser.WithAttribute())}, tt.opts...)
+			md := goldmark.New(
+				goldmark.WithExtensions(New(opts...)),
+				goldmark.WithRendererOptions(html.WithUnsafe()),
+			)
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+
+			if tt.executed == "" {
+				return
+			}
+			tmpl, err := template.New("page").Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, data); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.executed {
+				t.Errorf("Executed mismatch\nExpected: %q\nGot:      %q", tt.executed, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestBlockAttributeLists(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "paragraph",
+			input:    "Hello {{ .Name }}\n{: .note data-user=\"{{ .User.ID }}\"}",
+			expected: `<p class="note" data-user="{{ .User.ID }}">Hello {{ .Name }}</p>`,
+		},
+		{
+			name:     "list",
+			input:    "- a\n- b\n{: #{{ .ListID }} .items}",
+			expected: "<ul id=\"{{ .ListID }}\" class=\"items\">\n<li>a</li>\n<li>b</li>\n</ul>",
+		},
+		{
+			name:     "ordered list",
+			input:    "3. a\n{: type=\"{{ .Style }}\"}",
+			expected: "<ol start=\"3\" type=\"{{ .Style }}\">\n<li>a</li>\n</ol>",
+		},
+		{
+			name:     "blockquote",
+			input:    "> quote\n{: cite={{ .Source }}}",
+			expected: "<blockquote cite=\"{{ .Source }}\">\n<p>quote</p>\n</blockquote>",
+		},
+		{
+			name:     "fenced code block",
+			input:    "```go\nx := 1\n```\n{: data-file=\"{{ .Path }}\"}",
+			expected: "<pre data-file=\"{{ .Path }}\"><code class=\"language-go\">x := 1\n</code></pre>",
+		},
+		{
+			name:     "table",
+			input:    "| a |\n|---|\n| {{ .A }} |\n{: .grid title=\"{{ printf \"%q\" .T }}\"}",
+			expected: "<table class=\"grid\" title=\"{{ printf \"%q\" .T }}\">\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>{{ .A }}</td>\n</tr>\n</tbody>\n</table>",
+		},
+		{
+			name:     "bare action",
+			input:    "Text\n{: {{ if .Hidden }}hidden{{ end }}}",
+			expected: `<p {{ if .Hidden }}hidden{{ end }}>Text</p>`,
+		},
+		{
+			name:     "bare action closed after the list",
+			input:    "Text\n{: {{ if .Hidden }} } text {{ end }}}",
+			expected: "<p>Text\n{: {{ if .Hidden }} } text {{ end }}}</p>",
+		},
+		{
+			name:     "not an attribute list",
+			input:    "{: .first}\n\nText\n{: .a} more",
+			expected: "<p>{: .first}</p>\n<p>Text\n{: .a} more</p>",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(
+			New(ParserOptions(parser.WithAttribute())),
+			extension.Table,
+		),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+			if _, err := template.New("page").Parse(got); err != nil {
+				t.Errorf("Output does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestBlockAttributeListsNeedAttributeOption(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(New()))
+	var buf bytes.Buffer
+	if err := md.Convert([]byte("Text\n{: .note}"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<p>Text\n{: .note}</p>\n"
+	if buf.String() != expected {
+		t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/hermit-ink/goldmark-template/cmd/goldmark-template
+
+go 1.22
+
+require (
+	github.com/hermit-ink/goldmark-template v0.0.0-00010101000000-000000000000
+	github.com/yuin/goldmark v1.7.13
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/hermit-ink/goldmark-template => ../..
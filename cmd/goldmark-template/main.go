@@ -0,0 +1,318 @@
+// Command goldmark-template converts Markdown templates to html/template
+// source, checks them, and renders them against sample data.
+//
+// Usage:
+//
+//	goldmark-template [flags] [file.md ...]
+//
+// With no flags, each file (or standard input) is converted and the
+// html/template source is written to standard output. With -check, the
+// files are validated and parsed instead, and every problem is reported as
+// file:line:column against the Markdown. With -data, each file is executed
+// with the JSON or YAML data in the given file and the final HTML is
+// written to standard output. The files are parsed into one template set,
+// named as they are on the command line, so they can call each other with
+// {{ template "partials/footer.md" . }}. With -schema or -struct, the data
+// read by all the files together is described as a JSON Schema or as Go
+// types.
+//
+// Templates that call functions of their own, which only the program that
+// executes them provides, can be checked and executed with -funcs: each
+// function named is a stub that takes any arguments and returns no value.
+//
+// When more than one file is converted or executed, each output is preceded
+// by an HTML comment naming its file.
+//
+// The exit status is 0 on success, 1 if a template has problems or fails to
+// execute, and 2 for usage and I/O errors.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	goldmarktemplate "github.com/hermit-ink/goldmark-template"
+	"github.com/hermit-ink/goldmark-template/ast"
+	"github.com/hermit-ink/goldmark-template/schema"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	"github.com/hermit-ink/goldmark-template/validate"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit statuses.
+const (
+	exitOK      = 0
+	exitProblem = 1
+	exitUsage   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type options struct {
+	check     bool
+	dataFile  string
+	delims    string
+	attribute bool
+	schema    bool
+	goStruct  string
+	funcs     string
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("goldmark-template", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var opts options
+	flags.BoolVar(&opts.check, "check", false, "validate the templates instead of converting them")
+	flags.StringVar(&opts.dataFile, "data", "", "execute the templates with the data in this `file` (.json, .yaml or .yml)")
+	flags.StringVar(&opts.delims, "delims", "", "action delimiters separated by a space, such as \"[[ ]]\"")
+	flags.BoolVar(&opts.attribute, "attribute", false, "enable {...} attributes on headings")
+	flags.BoolVar(&opts.schema, "schema", false, "print a JSON Schema for the data the templates read")
+	flags.StringVar(&opts.goStruct, "struct", "", "print Go types for the data the templates read, with the root type called `name`")
+	flags.StringVar(&opts.funcs, "funcs", "", "comma-separated `names` of functions the templates call, declared as stubs that return no value")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: goldmark-template [flags] [file.md ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if modes := usedModes(opts); len(modes) > 1 {
+		fmt.Fprintf(stderr, "goldmark-template: %s cannot be used together\n", strings.Join(modes, " and "))
+		return exitUsage
+	}
+
+	left, right, err := parseDelims(opts.delims)
+	if err != nil {
+		fmt.Fprintf(stderr, "goldmark-template: %v\n", err)
+		return exitUsage
+	}
+
+	var data any
+	if opts.dataFile != "" {
+		if data, err = readData(opts.dataFile); err != nil {
+			fmt.Fprintf(stderr, "goldmark-template: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	extOpts := []goldmarktemplate.Option{goldmarktemplate.WithDelims(left, right)}
+	if opts.check {
+		extOpts = append(extOpts, goldmarktemplate.WithValidation())
+	}
+	if opts.attribute {
+		extOpts = append(extOpts, goldmarktemplate.ParserOptions(parser.WithAttribute()))
+	}
+	ext := goldmarktemplate.New(extOpts...)
+	md := goldmark.New(
+		goldmark.WithExtensions(ext),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	funcs := stubFuncs(opts.funcs)
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	describe := opts.schema || opts.goStruct != ""
+	var refs []ast.Reference
+	set := template.New("").Funcs(funcs)
+	sourceMaps := goldmarktemplate.SourceMaps{}
+	var names []string
+	status := exitOK
+	for _, file := range files {
+		source, err := readSource(file, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "goldmark-template: %v\n", err)
+			return exitUsage
+		}
+		name := file
+		if file == "-" {
+			name = "<stdin>"
+		}
+
+		switch {
+		case describe:
+			doc := md.Parser().Parse(text.NewReader(source))
+			refs = append(refs, ast.References(doc, source, tutil.NewDelimiters(left, right))...)
+		case opts.check || opts.dataFile != "":
+			tmpl, err := sourceMaps.Compile(name, source, funcs, goldmark.WithExtensions(ext))
+			if err != nil {
+				reportError(stderr, name, err)
+				status = exitProblem
+				continue
+			}
+			// Add the templates of every file to one set, so that each
+			// can call the others.
+			for _, t := range tmpl.Templates() {
+				if _, err := set.AddParseTree(t.Name(), t.Tree); err != nil {
+					fmt.Fprintf(stderr, "%s: %v\n", name, err)
+					status = exitProblem
+				}
+			}
+			names = append(names, name)
+		default:
+			var buf bytes.Buffer
+			if err := md.Convert(source, &buf); err != nil {
+				reportError(stderr, name, err)
+				status = exitProblem
+				continue
+			}
+			if err := writeOutput(stdout, name, buf.Bytes(), len(files) > 1); err != nil {
+				fmt.Fprintf(stderr, "goldmark-template: %v\n", err)
+				return exitUsage
+			}
+		}
+	}
+	if describe {
+		return writeShape(stdout, stderr, schema.Infer(refs), opts)
+	}
+
+	if opts.dataFile != "" {
+		// Execute only once every file is parsed, so that each can call the
+		// templates of the others.
+		for _, name := range names {
+			var out bytes.Buffer
+			if err := set.ExecuteTemplate(&out, name, data); err != nil {
+				fmt.Fprintln(stderr, sourceMaps.RewriteError(err))
+				status = exitProblem
+				continue
+			}
+			if err := writeOutput(stdout, name, out.Bytes(), len(files) > 1); err != nil {
+				fmt.Fprintf(stderr, "goldmark-template: %v\n", err)
+				return exitUsage
+			}
+		}
+	}
+	return status
+}
+
+// writeOutput writes the output of the file name, preceded by an HTML
+// comment naming the file if there are several outputs.
+func writeOutput(w io.Writer, name string, output []byte, several bool) error {
+	if several {
+		if _, err := fmt.Fprintf(w, "<!-- %s -->\n", name); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(output)
+	return err
+}
+
+// stubFuncs returns a stub for each of the comma-separated function names,
+// which takes any arguments and returns no value.
+func stubFuncs(names string) template.FuncMap {
+	funcs := template.FuncMap{}
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			funcs[name] = func(...any) any { return nil }
+		}
+	}
+	return funcs
+}
+
+// usedModes returns the flags given that select what the command does.
+func usedModes(opts options) []string {
+	var modes []string
+	if opts.check {
+		modes = append(modes, "-check")
+	}
+	if opts.dataFile != "" {
+		modes = append(modes, "-data")
+	}
+	if opts.schema {
+		modes = append(modes, "-schema")
+	}
+	if opts.goStruct != "" {
+		modes = append(modes, "-struct")
+	}
+	return modes
+}
+
+func writeShape(stdout, stderr io.Writer, shape *schema.Shape, opts options) int {
+	var out []byte
+	var err error
+	if opts.schema {
+		out, err = schema.JSONSchema(shape)
+		out = append(out, '\n')
+	} else {
+		out, err = schema.GoStruct(shape, opts.goStruct)
+	}
+	if err == nil {
+		_, err = stdout.Write(out)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "goldmark-template: %v\n", err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// reportError prints each validation problem in err on a line of its own,
+// prefixed with the file name like a compiler error. The errors of
+// html/template already name the file.
+func reportError(w io.Writer, name string, err error) {
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		if strings.HasPrefix(err.Error(), "template: ") {
+			fmt.Fprintln(w, err)
+		} else {
+			fmt.Fprintf(w, "%s: %v\n", name, err)
+		}
+		return
+	}
+	for _, e := range errs {
+		fmt.Fprintf(w, "%s:%v\n", name, e)
+	}
+}
+
+func parseDelims(s string) (string, string, error) {
+	if s == "" {
+		return "", "", nil
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("-delims must be two delimiters separated by a space, got %q", s)
+	}
+	return fields[0], fields[1], nil
+}
+
+func readSource(file string, stdin io.Reader) ([]byte, error) {
+	if file == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(file)
+}
+
+func readData(file string) (any, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var data any
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		err = json.Unmarshal(b, &data)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &data)
+	default:
+		return nil, fmt.Errorf("%s: data must be a .json, .yaml or .yml file", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	page := write("page.md", "# {{ .Title }}\n\n{{ range .Items }}\n- {{ . }}\n{{ end }}")
+	broken := write("broken.md", "Intro\n\nHello {{ .Title }")
+	failing := write("failing.md", "Intro\n\n{{ index .Items 5 }}")
+	footer := write("footer.md", "Intro\n\nBy {{ .Author }}")
+	withFooter := write("with-footer.md", "# {{ .Title }}\n\n{{ template \""+footer+"\" . }}")
+	callsFailing := write("calls-failing.md", "{{ template \""+failing+"\" . }}")
+	jsonData := write("data.json", `{"Title": "Home", "Items": ["a", "b"]}`)
+	yamlData := write("data.yaml", "Title: Home\nItems:\n  - a\n  - b\n")
+	textData := write("data.txt", "Title=Home")
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		status int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "convert file",
+			args:   []string{page},
+			stdout: "<h1>{{ .Title }}</h1>\n{{ range .Items }}\n<ul>\n<li>{{ . }}</li>\n</ul>\n{{ end }}\n",
+		},
+		{
+			name:   "convert stdin",
+			stdin:  "Hello [[ .Name ]]",
+			args:   []string{"-delims", "[[ ]]"},
+			stdout: "<p>Hello [[ .Name ]]</p>\n",
+		},
+		{
+			name:   "check passes",
+			args:   []string{"-check", page},
+			status: exitOK,
+		},
+		{
+			name:   "check reports Markdown positions",
+			args:   []string{"-check", page, broken},
+			status: exitProblem,
+			stderr: broken + ":3:7: {{ .Title }: unclosed action\n",
+		},
+		{
+			name:   "check reports parse errors",
+			stdin:  "Text {{ shout .X }}",
+			args:   []string{"-check"},
+			status: exitProblem,
+			stderr: `template: <stdin>:1:6: function "shout" not defined`,
+		},
+		{
+			name:   "check with function stubs",
+			stdin:  "Text {{ shout .X }}",
+			args:   []string{"-check", "-funcs", "shout, slug"},
+			status: exitOK,
+		},
+		{
+			name:   "execute with function stubs",
+			stdin:  "# {{ .Title }}\n\n{{ range slug .Items }}x{{ end }}{{ shout .Title }}",
+			args:   []string{"-funcs", "slug,shout", "-data", jsonData},
+			stdout: "<h1>Home</h1>\n<p></p>\n",
+		},
+		{
+			name:   "execute with JSON",
+			args:   []string{"-data", jsonData, page},
+			stdout: "<h1>Home</h1>\n\n<ul>\n<li>a</li>\n</ul>\n\n<ul>\n<li>b</li>\n</ul>\n\n",
+		},
+		{
+			name:   "execute with YAML",
+			args:   []string{"-data", yamlData, page},
+			stdout: "<h1>Home</h1>\n\n<ul>\n<li>a</li>\n</ul>\n\n<ul>\n<li>b</li>\n</ul>\n\n",
+		},
+		{
+			name:   "convert several files",
+			args:   []string{page, footer},
+			stdout: "<!-- " + page + " -->\n<h1>{{ .Title }}</h1>\n{{ range .Items }}\n<ul>\n<li>{{ . }}</li>\n</ul>\n{{ end }}\n<!-- " + footer + " -->\n<p>Intro</p>\n<p>By {{ .Author }}</p>\n",
+		},
+		{
+			name:   "execute files that call each other",
+			args:   []string{"-data", jsonData, withFooter, footer},
+			stdout: "<!-- " + withFooter + " -->\n<h1>Home</h1>\n<p>Intro</p>\n<p>By </p>\n\n<!-- " + footer + " -->\n<p>Intro</p>\n<p>By </p>\n",
+		},
+		{
+			name:   "execution error points at the called file",
+			args:   []string{"-data", jsonData, callsFailing, failing},
+			status: exitProblem,
+			stderr: "template: " + failing + ":3:4: executing \"" + failing + "\" at <index .Items 5>",
+		},
+		{
+			name:   "execution error points at the Markdown",
+			args:   []string{"-data", jsonData, failing},
+			status: exitProblem,
+			stderr: "template: " + failing + ":3:4: executing",
+		},
+		{
+			name:   "Go types for the data",
+			args:   []string{"-struct", "Page", page},
+			stdout: "type Page struct {\n\tItems []any `json:\"Items\"`\n\tTitle any   `json:\"Title\"`\n}\n",
+		},
+		{
+			name:   "JSON Schema for the data",
+			stdin:  "[x]({{ .URL }})",
+			args:   []string{"-schema"},
+			stdout: "{\n  \"$schema\": \"https://json-schema.org/draft/2020-12/schema\",\n  \"properties\": {\n    \"URL\": {\n      \"type\": \"string\"\n    }\n  },\n  \"required\": [\n    \"URL\"\n  ],\n  \"type\": \"object\"\n}\n",
+		},
+		{
+			name:   "unknown data format",
+			args:   []string{"-data", textData, page},
+			status: exitUsage,
+			stderr: "data must be a .json, .yaml or .yml file",
+		},
+		{
+			name:   "missing file",
+			args:   []string{filepath.Join(dir, "missing.md")},
+			status: exitUsage,
+			stderr: "no such file or directory",
+		},
+		{
+			name:   "bad delimiters",
+			args:   []string{"-delims", "[["},
+			status: exitUsage,
+			stderr: "-delims must be two delimiters",
+		},
+		{
+			name:   "schema and struct together",
+			args:   []string{"-schema", "-struct", "Page", page},
+			status: exitUsage,
+			stderr: "-schema and -struct cannot be used together",
+		},
+		{
+			name:   "check and data together",
+			args:   []string{"-check", "-data", jsonData, page},
+			status: exitUsage,
+			stderr: "cannot be used together",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if status != tt.status {
+				t.Errorf("status: expected %d, got %d (stderr %q)", tt.status, status, stderr.String())
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout mismatch\nExpected: %q\nGot:      %q", tt.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) || (tt.stderr == "" && stderr.Len() > 0) {
+				t.Errorf("stderr: expected %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
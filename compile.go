@@ -0,0 +1,165 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+
+	"github.com/hermit-ink/goldmark-template/renderer/html"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	"github.com/yuin/goldmark"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	ghtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// Compile converts the Markdown source to HTML and parses the result as an
+// html/template named name, with funcs added to its function map.
+//
+// The goldmark instance is built with html.WithUnsafe, followed by opts. To
+// configure the extension, for example with WithValidation or WithDelims,
+// pass goldmark.WithExtensions(New(...)) in opts; the template is then parsed
+// with the same delimiters. Otherwise the extension is added with its
+// default options. Parser options passed with goldmark.WithParserOptions
+// apply to the parser of the extension.
+//
+// Conversion and parse errors are combined into the returned error, and the
+// positions in parse errors are rewritten to point at the Markdown source.
+// Errors from executing the template report positions in the HTML; use
+// SourceMaps.Compile to rewrite those too.
+func Compile(name string, source []byte, funcs template.FuncMap, opts ...goldmark.Option) (*template.Template, error) {
+	return newCompiler(opts).compile(template.New(name).Funcs(funcs), name, source, nil)
+}
+
+// SourceMaps holds the source maps of the templates compiled from Markdown,
+// by template name. Compile and ParseFSInto through it to keep the maps, and
+// rewrite the errors from executing the templates with RewriteError:
+//
+//	maps := goldmarktemplate.SourceMaps{}
+//	tmpl, err := maps.Compile("page.md", source, funcs)
+//	...
+//	if err := tmpl.Execute(w, data); err != nil {
+//		return maps.RewriteError(err)
+//	}
+type SourceMaps map[string]*html.SourceMap
+
+// Compile is like the Compile function, and records the source map of the
+// template in m.
+func (m SourceMaps) Compile(name string, source []byte, funcs template.FuncMap, opts ...goldmark.Option) (*template.Template, error) {
+	return newCompiler(opts).compile(template.New(name).Funcs(funcs), name, source, m)
+}
+
+// RewriteError rewrites the positions that html/template errors report in
+// the HTML of the templates in m to point at their Markdown source, as
+// html.SourceMap.RewriteError does for a single template. An error from
+// executing one template may report a position in another one that it
+// calls. The returned error wraps err.
+func (m SourceMaps) RewriteError(err error) error {
+	for name, sm := range m {
+		err = sm.RewriteError(err, name)
+	}
+	return err
+}
+
+// Must is a helper that wraps a call to Compile and panics if the error is
+// non-nil, like template.Must.
+func Must(t *template.Template, err error) *template.Template {
+	return template.Must(t, err)
+}
+
+// compiler converts Markdown and parses the result into templates.
+type compiler struct {
+	md    goldmark.Markdown
+	probe *delimsProbe
+}
+
+func newCompiler(opts []goldmark.Option) *compiler {
+	probe := &delimsProbe{delims: tutil.DefaultDelimiters}
+	p := &compileParser{Parser: goldmark.DefaultParser()}
+	options := []goldmark.Option{
+		goldmark.WithParser(p),
+		goldmark.WithRendererOptions(ghtml.WithUnsafe()),
+	}
+	options = append(options, opts...)
+	options = append(options,
+		goldmark.WithExtensions(&compileExtension{parser: p}),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(util.Prioritized(probe, 1000)),
+		),
+	)
+	return &compiler{
+		md:    goldmark.New(options...),
+		probe: probe,
+	}
+}
+
+// compile converts source and parses it into t, which must be named name,
+// and records its source map in maps unless maps is nil.
+func (c *compiler) compile(t *template.Template, name string, source []byte, maps SourceMaps) (*template.Template, error) {
+	var buf bytes.Buffer
+	sm := html.NewSourceMap(&buf, source)
+	if maps != nil {
+		maps[name] = sm
+	}
+	convertErr := c.md.Convert(source, sm)
+	if convertErr != nil {
+		convertErr = fmt.Errorf("%s: %w", name, convertErr)
+	}
+
+	tmpl, parseErr := t.Delims(string(c.probe.delims.Left), string(c.probe.delims.Right)).
+		Parse(buf.String())
+	if err := errors.Join(convertErr, sm.RewriteError(parseErr, name)); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// compileParser is the parser of a goldmark instance built by newCompiler
+// until the extension replaces it. It keeps the options it is given, so that
+// they can be applied to the parser of the extension, and notes whether the
+// caller passed an extension of their own.
+type compileParser struct {
+	gparser.Parser
+	options  []gparser.Option
+	extended bool
+}
+
+// AddOptions implements parser.Parser.AddOptions.
+func (p *compileParser) AddOptions(opts ...gparser.Option) {
+	p.options = append(p.options, opts...)
+	p.Parser.AddOptions(opts...)
+}
+
+// compileExtension comes after the extensions passed to Compile. It adds the
+// extension with its default options unless one of them was the extension,
+// and then applies the parser options to its parser.
+type compileExtension struct {
+	parser *compileParser
+}
+
+// Extend implements goldmark.Extender.
+func (e *compileExtension) Extend(m goldmark.Markdown) {
+	if !e.parser.extended {
+		New().Extend(m)
+	}
+	m.Parser().AddOptions(e.parser.options...)
+}
+
+// delimsProbe is a node renderer that renders nothing. It only picks up the
+// delimiters the extension was configured with, so that the converted
+// output is parsed with the same ones.
+type delimsProbe struct {
+	delims tutil.Delimiters
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (p *delimsProbe) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {}
+
+// SetOption implements renderer.SetOptioner.
+func (p *delimsProbe) SetOption(name renderer.OptionName, value interface{}) {
+	if name == html.OptDelims {
+		p.delims = value.(tutil.Delimiters)
+	}
+}
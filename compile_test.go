@@ -0,0 +1,168 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hermit-ink/goldmark-template/validate"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []goldmark.Option
+		data     any
+		expected string
+	}{
+		{
+			name:     "actions and funcs",
+			input:    "# {{ .Title | upper }}\n\n[Home]({{ .URL }})",
+			data:     map[string]any{"Title": "hello", "URL": "/home"},
+			expected: "<h1>HELLO</h1>\n<p><a href=\"/home\">Home</a></p>\n",
+		},
+		{
+			name:     "raw HTML is kept",
+			input:    "<div class=\"{{ .Class }}\">x</div>",
+			data:     map[string]any{"Class": "note"},
+			expected: "<div class=\"note\">x</div>",
+		},
+		{
+			name:     "goldmark options",
+			input:    "| a |\n|---|\n| {{ .Cell }} |",
+			opts:     []goldmark.Option{goldmark.WithExtensions(extension.Table)},
+			data:     map[string]any{"Cell": "1"},
+			expected: "<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "custom delimiters",
+			input:    "Hello [[ .Name ]] {{ literal }}",
+			opts:     []goldmark.Option{goldmark.WithExtensions(New(WithDelims("[[", "]]")))},
+			data:     map[string]any{"Name": "Ann"},
+			expected: "<p>Hello Ann {{ literal }}</p>\n",
+		},
+		{
+			name:     "parser options",
+			input:    "# Hello {{ .Name }}",
+			opts:     []goldmark.Option{goldmark.WithParserOptions(parser.WithAutoHeadingID())},
+			data:     map[string]any{"Name": "Ann"},
+			expected: "<h1 id=\"hello--name-\">Hello Ann</h1>\n",
+		},
+		{
+			name:  "parser options with configured extension",
+			input: "# Title {.a}\n\n[Home](/){.b}",
+			opts: []goldmark.Option{
+				goldmark.WithExtensions(New(WithLinkAttributes())),
+				goldmark.WithParserOptions(parser.WithAttribute()),
+			},
+			expected: "<h1 class=\"a\">Title</h1>\n<p><a href=\"/\" class=\"b\">Home</a></p>\n",
+		},
+	}
+
+	funcs := template.FuncMap{"upper": strings.ToUpper}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Compile("page", []byte(tt.input), funcs, tt.opts...)
+			if err != nil {
+				t.Fatalf("Compile returned error: %v", err)
+			}
+			if tmpl.Name() != "page" {
+				t.Errorf("expected template name %q, got %q", "page", tmpl.Name())
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, tt.data); err != nil {
+				t.Fatalf("Failed to execute template: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	t.Run("parse error points at the Markdown", func(t *testing.T) {
+		_, err := Compile("page", []byte("Intro\n\nText {{ .A ( }}"), nil)
+		if err == nil || !strings.Contains(err.Error(), "template: page:3:6: unclosed left paren") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("undefined function", func(t *testing.T) {
+		_, err := Compile("page", []byte("{{ shout .X }}"), nil)
+		if err == nil || !strings.Contains(err.Error(), `function "shout" not defined`) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("conversion errors are kept", func(t *testing.T) {
+		_, err := Compile("page", []byte("Hello {{ .Title }"), nil,
+			goldmark.WithExtensions(New(WithValidation())))
+		var errs validate.Errors
+		if !errors.As(err, &errs) {
+			t.Fatalf("expected validate.Errors, got %v", err)
+		}
+		if !strings.HasPrefix(err.Error(), "page: 1:7:") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Must panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected Must to panic")
+			}
+		}()
+		Must(Compile("page", []byte("{{ if }}"), nil))
+	})
+}
+
+func TestSourceMaps(t *testing.T) {
+	data := map[string]any{"User": nil}
+
+	t.Run("Compile maps exec errors", func(t *testing.T) {
+		maps := SourceMaps{}
+		tmpl, err := maps.Compile("page.md", []byte("# Title\n\nSome text\n\nHello {{ .User.Name }}!"), nil)
+		if err != nil {
+			t.Fatalf("Compile returned error: %v", err)
+		}
+		err = tmpl.Execute(&bytes.Buffer{}, data)
+		if err == nil {
+			t.Fatalf("expected the template to fail")
+		}
+		expected := "template: page.md:5:15: executing"
+		if got := maps.RewriteError(err); !strings.Contains(got.Error(), expected) || !errors.Is(got, err) {
+			t.Errorf("expected %q in %q (original %q)", expected, got, err)
+		}
+	})
+
+	t.Run("ParseFSInto maps exec errors in called files", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"page.md":    {Data: []byte("# Title\n\nText\n\n{{ template \"partial.md\" . }}")},
+			"partial.md": {Data: []byte("Intro\n\nMore text\n\nHello {{ .User.Name }}!")},
+		}
+		maps := SourceMaps{}
+		tmpl, err := maps.ParseFSInto(nil, fsys, nil, "*.md")
+		if err != nil {
+			t.Fatalf("ParseFSInto returned error: %v", err)
+		}
+		if len(maps) != 2 {
+			t.Errorf("expected 2 source maps, got %d", len(maps))
+		}
+		err = tmpl.ExecuteTemplate(&bytes.Buffer{}, "page.md", data)
+		if err == nil {
+			t.Fatalf("expected the template to fail")
+		}
+		expected := "template: partial.md:5:15: executing"
+		if got := maps.RewriteError(err); !strings.Contains(got.Error(), expected) {
+			t.Errorf("expected %q in %q (original %q)", expected, got, err)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hermit-ink/goldmark-template/validate"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+type typeCheckPage struct {
+	Title  string
+	URL    string
+	Author *typeCheckUser
+	Posts  []typeCheckPost
+	Params map[string]string
+	Extra  any
+	Count  int
+}
+
+type typeCheckUser struct {
+	Name string
+}
+
+func (u *typeCheckUser) Greet(greeting string) string {
+	return greeting + " " + u.Name
+}
+
+type typeCheckPost struct {
+	Title string
+	Tags  []string
+}
+
+func (p typeCheckPost) Summary() (string, error) {
+	return p.Title, nil
+}
+
+func TestWithDataType(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "valid document",
+			input:    "# {{ .Title }}\n\n[home]({{ .URL }}) by {{ .Author.Name }} {{ .Author.Greet \"hi\" }}\n\n{{ range .Posts }}\n- {{ .Summary }} {{ range .Tags }}{{ . }}{{ end }} {{ $.Title }}\n{{ end }}\n\n{{ index .Params \"lang\" }} {{ .Params.lang }} {{ .Extra.Anything }} {{ range .Count }}{{ . }}{{ end }}",
+			expected: nil,
+		},
+		{
+			name:     "misspelled field",
+			input:    "Intro\n\n# {{ .Titel }}",
+			expected: []string{"3:6: {{ .Titel }}: can't evaluate field Titel in type goldmarktemplate.typeCheckPage"},
+		},
+		{
+			name:     "field in link destination",
+			input:    "[x]({{ .Author.Email }})",
+			expected: []string{"1:8: {{ .Author.Email }}: can't evaluate field Email in type goldmarktemplate.typeCheckUser"},
+		},
+		{
+			name:     "dot rebound by range",
+			input:    "{{ range .Posts }}\n* {{ .Titel }}\n{{ end }}",
+			expected: []string{"2:6: {{ .Titel }}: can't evaluate field Titel in type goldmarktemplate.typeCheckPost"},
+		},
+		{
+			name:     "dot rebound by with and variables",
+			input:    "{{ with $a := .Author }}{{ .Name }} {{ $a.Nick }}{{ end }}",
+			expected: []string{"1:40: {{ $a.Nick }}: can't evaluate field Nick in type goldmarktemplate.typeCheckUser"},
+		},
+		{
+			name:  "method arity",
+			input: "{{ .Author.Greet }} {{ .Posts.Summary }}\n\n{{ range .Posts }}{{ .Summary \"x\" }}{{ end }}",
+			expected: []string{
+				"1:4: {{ .Author.Greet }}: wrong number of args for Greet: want 1 got 0",
+				"1:24: {{ .Posts.Summary }}: can't evaluate field Summary in type []goldmarktemplate.typeCheckPost",
+				"3:22: {{ .Summary \"x\" }}: wrong number of args for Summary: want 0 got 1",
+			},
+		},
+		{
+			name:     "field with arguments",
+			input:    "{{ \"x\" | .Title }}",
+			expected: []string{"1:10: {{ \"x\" | .Title }}: Title has arguments but cannot be invoked as function"},
+		},
+		{
+			name:     "range over a value that is not a collection",
+			input:    "{{ range .Title }}{{ . }}{{ end }}",
+			expected: []string{"1:10: {{ range .Title }}: range can't iterate over string"},
+		},
+		{
+			name:     "unknown scope reported once",
+			input:    "{{ with .Autor }}{{ .Name }}{{ end }}",
+			expected: []string{"1:9: {{ with .Autor }}: can't evaluate field Autor in type goldmarktemplate.typeCheckPage"},
+		},
+		{
+			name:     "define bodies are not checked",
+			input:    "{{ define \"x\" }}\n{{ .Anything }}\n{{ end }}",
+			expected: nil,
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(
+			WithDataType(reflect.TypeOf(typeCheckPage{})),
+			ParserOptions(parser.WithAttribute()),
+		)),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := md.Convert([]byte(tt.input), &buf)
+
+			var got []string
+			if err != nil {
+				var errs validate.Errors
+				if !errors.As(err, &errs) {
+					t.Fatalf("expected validate.Errors, got %T: %v", err, err)
+				}
+				for _, e := range errs {
+					got = append(got, e.Error())
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Errors mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
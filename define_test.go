@@ -0,0 +1,105 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestDefineBodies(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "define is hoisted to the end",
+			input:    "# Page\n\n{{ define \"intro\" }}\nWelcome\n{{ end }}\n\nMain text",
+			expected: "<h1>Page</h1>\n<p>Main text</p>\n{{ define \"intro\" }}<p>Welcome</p>\n{{ end -}}",
+		},
+		{
+			name:     "consecutive defines are emitted on lines of their own",
+			input:    "{{ define \"a\" }}\nA\n{{ end }}\n{{ define \"b\" }}\n- B\n{{ end }}",
+			expected: "{{ define \"a\" }}<p>A</p>\n{{ end -}}\n{{ define \"b\" }}<ul>\n<li>B</li>\n</ul>\n{{ end -}}",
+		},
+		{
+			name:     "define inside a block stays in place",
+			input:    "{{ if .A }}\n{{ define \"x\" }}\nX\n{{ end }}\nA\n{{ end }}",
+			expected: "{{ if .A }}\n{{ define \"x\" }}<p>X</p>\n{{ end }}<p>A</p>\n{{ end }}",
+		},
+		{
+			name:     "block stays in place",
+			input:    "Before\n\n{{ block \"sidebar\" . }}\nDefault *text*\n{{ end }}\n\nAfter",
+			expected: "<p>Before</p>\n{{ block \"sidebar\" . }}<p>Default <em>text</em></p>\n{{ end }}\n<p>After</p>",
+		},
+		{
+			name:     "trim markers are dropped",
+			input:    "Before\n\n{{- block \"a\" . -}}\nA\n{{- end -}}",
+			expected: "<p>Before</p>\n{{ block \"a\" . }}<p>A</p>\n{{ end }}",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDefineBodiesEndWithLineBreak(t *testing.T) {
+	input := "# Page\n\n{{ define \"intro\" }}\nWelcome\n{{ end }}\n\nMain text\n"
+	expected := "<h1>Page</h1>\n<p>Main text</p>\n{{ define \"intro\" }}<p>Welcome</p>\n{{ end -}}\n"
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(input), &buf); err != nil {
+		t.Fatalf("Failed to convert markdown: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", expected, buf.String())
+	}
+}
+
+func TestDefineBodiesExecute(t *testing.T) {
+	input := "# {{ .Title }}\n\n{{ define \"intro\" }}\nWelcome *{{ .Name }}*\n\n- one\n{{ end }}\n\nMain text"
+	tmpl, err := Compile("page", []byte(input), nil)
+	if err != nil {
+		t.Fatalf("Compile returned error: %v", err)
+	}
+	data := map[string]string{"Title": "Page", "Name": "Ann"}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{template: "page", expected: "<h1>Page</h1>\n<p>Main text</p>\n"},
+		{template: "intro", expected: "<p>Welcome <em>Ann</em></p>\n<ul>\n<li>one</li>\n</ul>\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&out, tt.template, data); err != nil {
+			t.Fatalf("Failed to execute %q: %v", tt.template, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s: output mismatch\nExpected: %q\nGot:      %q", tt.template, tt.expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestCustomDelims(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "standalone action",
+			input:    "Hello [[ .Name ]]!",
+			expected: "<p>Hello [[ .Name ]]!</p>",
+		},
+		{
+			name:     "default braces are plain text",
+			input:    "Hello {{ .Name }} & [[ .Name ]]",
+			expected: "<p>Hello {{ .Name }} &amp; [[ .Name ]]</p>",
+		},
+		{
+			name:     "action in link destination",
+			input:    "[Profile]([[ .URL ]] \"[[ .Title ]]\")",
+			expected: "<p><a href=\"[[ .URL ]]\" title=\"[[ .Title ]]\">Profile</a></p>",
+		},
+		{
+			name:     "action in link text and destination",
+			input:    "[Go to [[ .Label ]]]([[ .URL ]])",
+			expected: "<p><a href=\"[[ .URL ]]\">Go to [[ .Label ]]</a></p>",
+		},
+		{
+			name:     "action in image",
+			input:    "![[[ .Alt ]]]([[ .Src ]])",
+			expected: "<p><img src=\"[[ .Src ]]\" alt=\"[[ .Alt ]]\" /></p>",
+		},
+		{
+			name:     "action with spaces in link destination",
+			input:    "[x]([[ index .Links \"home page\" ]])",
+			expected: "<p><a href=\"[[ index .Links \"home page\" ]]\">x</a></p>",
+		},
+		{
+			name:     "autolink",
+			input:    "<[[ .BaseURL ]]/page>",
+			expected: "<p><a href=\"[[ .BaseURL ]]/page\">[[ .BaseURL ]]/page</a></p>",
+		},
+		{
+			name:     "reference definition",
+			input:    "[home][ref]\n\n[ref]: [[ .URL ]]",
+			expected: "<p><a href=\"[[ .URL ]]\">home</a></p>",
+		},
+		{
+			name:     "code span",
+			input:    "`[[ printf \"`\" ]]`",
+			expected: "<p><code>[[ printf \"`\" ]]</code></p>",
+		},
+		{
+			name:     "heading attribute",
+			input:    "# Title {id=\"[[ .ID ]]\"}",
+			expected: "<h1 id=\"[[ .ID ]]\">Title</h1>",
+		},
+		{
+			name:     "block-level range",
+			input:    "[[ range .Items ]]\n* [[ . ]]\n[[ end ]]",
+			expected: "[[ range .Items ]]\n<ul>\n<li>[[ . ]]</li>\n</ul>\n[[ end ]]",
+		},
+		{
+			name:     "single brackets are still links",
+			input:    "[x](/y)",
+			expected: "<p><a href=\"/y\">x</a></p>",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(
+			New(
+				WithDelims("[[", "]]"),
+				ParserOptions(parser.WithAttribute()),
+			),
+		),
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+			html.WithXHTML(),
+		),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := md.Convert([]byte(tt.input), &buf)
+			if err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+
+			got := strings.TrimSpace(buf.String())
+			expected := strings.TrimSpace(tt.expected)
+
+			if got != expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestTemplateEscape(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		input    string
+		expected string
+		executed string
+	}{
+		{
+			name:     "escaped action",
+			input:    "Write \\{{ .Name }} to print a name",
+			expected: "<p>Write {{\"{{\"}} .Name }} to print a name</p>",
+			executed: "<p>Write {{ .Name }} to print a name</p>",
+		},
+		{
+			name:     "escaped and real actions",
+			input:    "# \\{{ .Title }} is {{ .Title }}",
+			expected: "<h1>{{\"{{\"}} .Title }} is {{ .Title }}</h1>",
+			executed: "<h1>{{ .Title }} is Home</h1>",
+		},
+		{
+			name:     "escaped backslash before an action",
+			input:    "\\\\{{ .Title }}",
+			expected: "<p>\\{{ .Title }}</p>",
+			executed: "<p>\\Home</p>",
+		},
+		{
+			name:     "escape inside emphasis and links",
+			input:    "*\\{{* [\\{{ x }}](/a)",
+			expected: "<p><em>{{\"{{\"}}</em> <a href=\"/a\">{{\"{{\"}} x }}</a></p>",
+			executed: "<p><em>{{</em> <a href=\"/a\">{{ x }}</a></p>",
+		},
+		{
+			name:     "no escapes in code spans",
+			input:    "`\\{{ .Title }}`",
+			expected: "<p><code>\\{{ .Title }}</code></p>",
+			executed: "<p><code>\\Home</code></p>",
+		},
+		{
+			name:     "custom delimiters",
+			opts:     []Option{WithDelims("[[", "]]")},
+			input:    "\\[[ .Title ]] and [[ .Title ]]",
+			expected: "<p>[[\"[[\"]] .Title ]] and [[ .Title ]]</p>",
+		},
+		{
+			name:     "custom escape",
+			opts:     []Option{WithEscape("@{{")},
+			input:    "@{{ .Title }} and {{ .Title }}",
+			expected: "<p>{{\"{{\"}} .Title }} and {{ .Title }}</p>",
+			executed: "<p>{{ .Title }} and Home</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := goldmark.New(
+				goldmark.WithExtensions(New(tt.opts...)),
+				goldmark.WithRendererOptions(html.WithUnsafe()),
+			)
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+
+			if tt.executed == "" {
+				return
+			}
+			tmpl, err := template.New("page").Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, map[string]string{"Title": "Home"}); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.executed {
+				t.Errorf("Executed mismatch\nExpected: %q\nGot:      %q", tt.executed, out.String())
+			}
+		})
+	}
+}
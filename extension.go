@@ -1,8 +1,14 @@
 package goldmarktemplate
 
 import (
+	"html/template"
+	"reflect"
+
+	"github.com/hermit-ink/goldmark-template/ast"
 	"github.com/hermit-ink/goldmark-template/parser"
 	"github.com/hermit-ink/goldmark-template/renderer/html"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	"github.com/hermit-ink/goldmark-template/validate"
 	"github.com/yuin/goldmark"
 	gparser "github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer"
@@ -12,34 +18,258 @@ import (
 // Extension is a goldmark extension for handling Go template actions
 type Extension struct {
 	parserOptions []gparser.Option
+	leftDelim     string
+	rightDelim    string
+	escape        string
+	validation    bool
+	dataType      reflect.Type
+	funcs         template.FuncMap
+	policy        ast.ActionPolicy
+	literalCode   bool
+	literalLangs  []string
+	headingIDs    bool
+	linkAttrs     bool
 }
 
+// An Option configures the Extension
+type Option func(*Extension)
+
 // New creates a new goldmark.Extender for template support
-func New() goldmark.Extender {
-	return &Extension{}
+func New(opts ...Option) goldmark.Extender {
+	e := &Extension{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 // WithParserOptions creates a new goldmark.Extender for template support with parser options
 func WithParserOptions(opts ...gparser.Option) goldmark.Extender {
-	return &Extension{parserOptions: opts}
+	return New(ParserOptions(opts...))
+}
+
+// ParserOptions is an Option that applies goldmark parser options to the
+// action-aware parser. It is the Option form of WithParserOptions.
+func ParserOptions(opts ...gparser.Option) Option {
+	return func(e *Extension) {
+		e.parserOptions = append(e.parserOptions, opts...)
+	}
+}
+
+// WithDelims is an Option that sets the action delimiters to match those
+// given to html/template's Template.Delims. An empty delimiter stands for
+// the default.
+func WithDelims(left, right string) Option {
+	return func(e *Extension) {
+		e.leftDelim = left
+		e.rightDelim = right
+	}
+}
+
+// WithEscape is an Option that sets the sequence that stands for a literal
+// left delimiter in text, in place of a backslash followed by the left
+// delimiter. It must start with an ASCII punctuation character.
+func WithEscape(escape string) Option {
+	return func(e *Extension) {
+		e.escape = escape
+	}
+}
+
+// WithValidation is an Option that checks every template action during
+// conversion, both on its own and for the nesting of block actions across
+// the whole document. Convert then fails with validate.Errors that locate
+// each problem in the Markdown source, instead of leaving it for
+// html/template to report against the generated HTML.
+func WithValidation() Option {
+	return func(e *Extension) {
+		e.validation = true
+	}
+}
+
+// WithDataType is an Option that checks during conversion that every field
+// chain in the document resolves against t, the type of the data the
+// template is executed with. Convert fails with validate.Errors for unknown
+// fields and for methods called with the wrong number of arguments, at
+// their Markdown position. See validate.Type.
+func WithDataType(t reflect.Type) Option {
+	return func(e *Extension) {
+		e.dataType = t
+	}
+}
+
+// WithFuncs is an Option that checks during conversion that every function
+// an action calls is in funcs or is an html/template builtin, and that it
+// is given the number of arguments its signature takes. Convert fails with
+// validate.Errors at the Markdown position of each bad call. See
+// validate.Funcs.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(e *Extension) {
+		e.funcs = funcs
+	}
+}
+
+// WithActionPolicy is an Option that sets what happens to the actions in
+// each context, for documents whose authors should not run actions
+// everywhere. Actions in a context with ast.PolicyLiteral are rendered so
+// that html/template prints them as text, and actions in a context with
+// ast.PolicyDeny make Convert fail with validate.Errors.
+//
+//	goldmarktemplate.WithActionPolicy(ast.ActionPolicy{
+//		ast.ContextRawHTML:   ast.PolicyDeny,
+//		ast.ContextCodeBlock: ast.PolicyLiteral,
+//	})
+func WithActionPolicy(policy ast.ActionPolicy) Option {
+	return func(e *Extension) {
+		e.policy = policy
+	}
+}
+
+// WithLiteralCode is an Option that renders the actions in code spans and
+// code blocks so that html/template prints them as text, for documents that
+// show template code. It is shorthand for ast.PolicyLiteral in
+// ast.ContextCodeSpan and ast.ContextCodeBlock, and leaves contexts that an
+// action policy denies denied.
+func WithLiteralCode() Option {
+	return func(e *Extension) {
+		e.literalCode = true
+	}
+}
+
+// WithLiteralLanguages is an Option that renders the actions in fenced code
+// blocks of the given languages as text, leaving other code to the action
+// policy:
+//
+//	goldmarktemplate.WithLiteralLanguages("gotemplate", "go-html-template")
+//
+// A literal attribute on a fenced code block, from an attribute list after
+// the fence or at the end of its info string, overrides its language:
+// literal=true renders it as text and literal=false does not. The actions
+// in literal blocks are not reported when an action policy denies code
+// blocks, as they are shown rather than executed. See ast.IsLiteralFence.
+func WithLiteralLanguages(languages ...string) Option {
+	return func(e *Extension) {
+		e.literalLangs = append(e.literalLangs, languages...)
+	}
+}
+
+// WithActionHeadingIDs is an Option that turns on auto heading IDs, and
+// gives headings with actions IDs that are generated when the template runs
+// instead of from their source. The heading # Intro to {{ .Title }} gets the
+// id {{ anchorize "Intro to " .Title }}, which a HeadingIDs provides:
+//
+//	tmpl.Funcs(goldmarktemplate.NewHeadingIDs().FuncMap())
+//
+// Every other heading without an explicit id also gets its ID when the
+// template runs, generated from its source as in {{ anchorize "Summary" }},
+// so that anchorize can keep all of them unique. That includes headings
+// whose actions do not print a single value, such as {{ if .Draft }}.
+func WithActionHeadingIDs() Option {
+	return func(e *Extension) {
+		e.headingIDs = true
+	}
+}
+
+// WithLinkAttributes is an Option that enables attribute lists right after
+// links and images:
+//
+//	![logo]({{ .Logo }}){width="{{ .W }}" loading="lazy"}
+//	[Buy]({{ .URL }}){.btn data-sku="{{ .SKU }}"}
+//
+// The lists take the same forms and actions as heading attribute lists.
+// URL attributes such as usemap are escaped like link destinations, and a
+// title attribute replaces the title of the link or image.
+func WithLinkAttributes() Option {
+	return func(e *Extension) {
+		e.linkAttrs = true
+	}
+}
+
+// actionPolicy returns the action policy with the code contexts made literal
+// if WithLiteralCode is set.
+func (e *Extension) actionPolicy() ast.ActionPolicy {
+	if !e.literalCode {
+		return e.policy
+	}
+	policy := ast.ActionPolicy{}
+	for c, rule := range e.policy {
+		policy[c] = rule
+	}
+	for _, c := range []ast.ActionContext{ast.ContextCodeSpan, ast.ContextCodeBlock} {
+		if policy.Rule(c) != ast.PolicyDeny {
+			policy[c] = ast.PolicyLiteral
+		}
+	}
+	return policy
 }
 
 // Extend configures the markdown processor to use our custom template action
 // handling
 func (e *Extension) Extend(m goldmark.Markdown) {
+	if p, ok := m.Parser().(*compileParser); ok {
+		// Compile adds the extension itself unless it is given one.
+		p.extended = true
+	}
+
 	// Create our new parser
-	newParser := parser.ActionAwareParsers()
-	
+	parserOpts := []parser.ActionOption{parser.WithDelims(e.leftDelim, e.rightDelim)}
+	if e.escape != "" {
+		parserOpts = append(parserOpts, parser.WithEscape(e.escape))
+	}
+	if e.headingIDs {
+		parserOpts = append(parserOpts, parser.WithHeadingIDFunc(HeadingIDFunc))
+	}
+	if e.linkAttrs {
+		parserOpts = append(parserOpts, parser.WithLinkAttributes())
+	}
+	newParser := parser.ActionAwareParsers(parserOpts...)
+
 	// Apply user-provided parser options
 	if len(e.parserOptions) > 0 {
 		newParser.AddOptions(e.parserOptions...)
 	}
-	
+	if e.headingIDs {
+		newParser.AddOptions(gparser.WithAutoHeadingID())
+	}
+
 	m.SetParser(newParser)
+	policy := e.actionPolicy()
 	m.Renderer().AddOptions(
 		renderer.WithNodeRenderers(
 			util.Prioritized(html.NewRenderer(), 100),
 			util.Prioritized(html.NewTemplateActionHTMLRenderer(), 500),
 		),
+		html.WithDelims(e.leftDelim, e.rightDelim),
+		html.WithPolicy(policy),
+		html.WithLiteralLanguages(e.literalLangs...),
 	)
+
+	delims := tutil.NewDelimiters(e.leftDelim, e.rightDelim)
+	var checks []validate.Check
+	if e.validation {
+		checks = append(checks, validate.Syntax(delims), validate.Balance(delims))
+	}
+	if e.dataType != nil {
+		checks = append(checks, validate.Type(delims, e.dataType))
+	}
+	if e.funcs != nil {
+		funcs := e.funcs
+		if e.headingIDs {
+			funcs = template.FuncMap{HeadingIDFunc: NewHeadingIDs().Anchorize}
+			for name, fn := range e.funcs {
+				funcs[name] = fn
+			}
+		}
+		checks = append(checks, validate.Funcs(delims, funcs))
+	}
+	for _, rule := range policy {
+		if rule == ast.PolicyDeny {
+			checks = append(checks, validate.Policy(delims, policy, e.literalLangs...))
+			break
+		}
+	}
+	if len(checks) > 0 {
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(
+			util.Prioritized(validate.NewRenderer(checks...), 50),
+		))
+	}
 }
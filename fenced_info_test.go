@@ -0,0 +1,96 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestFencedInfoActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		executed string
+	}{
+		{
+			name:     "action as language",
+			input:    "```{{ .Lang }}\nx\n```",
+			expected: "<pre><code class=\"language-{{ .Lang }}\">x\n</code></pre>",
+			executed: "<pre><code class=\"language-go\">x\n</code></pre>",
+		},
+		{
+			name:     "action with spaces and more words",
+			input:    "```{{ index .Langs 0 }} linenos\nx\n```",
+			expected: "<pre><code class=\"language-{{ index .Langs 0 }}\">x\n</code></pre>",
+			executed: "<pre><code class=\"language-rust\">x\n</code></pre>",
+		},
+		{
+			name:     "action inside a language",
+			input:    "~~~lang-{{ printf \"%s\" .Lang }}\nx\n~~~",
+			expected: "<pre><code class=\"language-lang-{{ printf \"%s\" .Lang }}\">x\n</code></pre>",
+			executed: "<pre><code class=\"language-lang-go\">x\n</code></pre>",
+		},
+		{
+			name:     "attribute list",
+			input:    "```go {data-file=\"{{ .Path }}\" .numbered}\nx := 1\n```",
+			expected: "<pre data-file=\"{{ .Path }}\" class=\"numbered\"><code class=\"language-go\">x := 1\n</code></pre>",
+			executed: "<pre data-file=\"main.go\" class=\"numbered\"><code class=\"language-go\">x := 1\n</code></pre>",
+		},
+		{
+			name:     "attribute list after an action",
+			input:    "```{{ .Lang }} {#{{ .ID }}}\nx\n```",
+			expected: "<pre id=\"{{ .ID }}\"><code class=\"language-{{ .Lang }}\">x\n</code></pre>",
+			executed: "<pre id=\"ex1\"><code class=\"language-go\">x\n</code></pre>",
+		},
+		{
+			name:     "attribute list only",
+			input:    "```{.numbered}\nx\n```",
+			expected: "<pre class=\"numbered\"><code>x\n</code></pre>",
+		},
+		{
+			name:     "not an attribute list",
+			input:    "```go {.a} b\nx\n```",
+			expected: "<pre><code class=\"language-go\">x\n</code></pre>",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(ParserOptions(parser.WithAttribute()))),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	data := map[string]any{"Lang": "go", "Langs": []string{"rust"}, "Path": "main.go", "ID": "ex1"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+
+			if tt.executed == "" {
+				return
+			}
+			tmpl, err := template.New("page").Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, data); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.executed {
+				t.Errorf("Executed mismatch\nExpected: %q\nGot:      %q", tt.executed, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/hermit-ink/goldmark-template/validate"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestWithFuncs(t *testing.T) {
+	funcs := template.FuncMap{
+		"asset":      func(path string) string { return "/static/" + path },
+		"t":          func(key string, args ...any) string { return key },
+		"formatDate": func(layout string, date any) (string, error) { return layout, nil },
+		"pair":       func() (string, string) { return "", "" },
+		"broken":     "not a function",
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "valid calls",
+			input:    "# {{ t \"title\" }}\n\n![logo]({{ asset \"logo.png\" }}) {{ formatDate \"2006\" .Date }} {{ .Date | formatDate \"2006\" }}\n\n{{ if eq .A 1 }}{{ printf \"%d\" (len .Items) }}{{ end }}",
+			expected: nil,
+		},
+		{
+			name:     "unknown function in link destination",
+			input:    "Intro\n\n[home]({{ url \"/\" }})",
+			expected: []string{"3:11: {{ url \"/\" }}: function \"url\" not defined"},
+		},
+		{
+			name:     "unknown function in heading attribute",
+			input:    "# Title {class=\"{{ theme .Page }}\"}",
+			expected: []string{"1:20: {{ theme .Page }}: function \"theme\" not defined"},
+		},
+		{
+			name:  "wrong argument counts",
+			input: "{{ asset }} {{ asset \"a\" \"b\" }} {{ t }}\n\n{{ \"x\" | asset \"y\" }}",
+			expected: []string{
+				"1:4: {{ asset }}: wrong number of args for asset: want 1 got 0",
+				"1:16: {{ asset \"a\" \"b\" }}: wrong number of args for asset: want 1 got 2",
+				"1:36: {{ t }}: wrong number of args for t: want at least 1 got 0",
+				"3:10: {{ \"x\" | asset \"y\" }}: wrong number of args for asset: want 1 got 2",
+			},
+		},
+		{
+			name:  "builtins",
+			input: "{{ len }} {{ not .A .B }} {{ lt .A }} {{ printf }}",
+			expected: []string{
+				"1:4: {{ len }}: wrong number of args for len: want 1 got 0",
+				"1:14: {{ not .A .B }}: wrong number of args for not: want 1 got 2",
+				"1:30: {{ lt .A }}: wrong number of args for lt: want 2 got 1",
+				"1:42: {{ printf }}: wrong number of args for printf: want at least 1 got 0",
+			},
+		},
+		{
+			name:  "bad FuncMap values",
+			input: "{{ pair }} {{ broken }}",
+			expected: []string{
+				"1:4: {{ pair }}: can't call method/function \"pair\" with 2 results",
+				"1:15: {{ broken }}: value for broken not a function",
+			},
+		},
+		{
+			name:     "code and define bodies",
+			input:    "`{{ missing }}`\n\n{{ define \"x\" }}\n{{ asset }}\n{{ end }}",
+			expected: []string{"1:5: {{ missing }}: function \"missing\" not defined", "4:4: {{ asset }}: wrong number of args for asset: want 1 got 0"},
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(
+			WithFuncs(funcs),
+			ParserOptions(parser.WithAttribute()),
+		)),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := md.Convert([]byte(tt.input), &buf)
+
+			var got []string
+			if err != nil {
+				var errs validate.Errors
+				if !errors.As(err, &errs) {
+					t.Fatalf("expected validate.Errors, got %T: %v", err, err)
+				}
+				for _, e := range errs {
+					got = append(got, e.Error())
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Errors mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package goldmarktemplate
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	gast "github.com/yuin/goldmark/ast"
+	gparser "github.com/yuin/goldmark/parser"
+)
+
+// HeadingIDFunc is the name of the template function that generates the IDs
+// of headings with actions when WithActionHeadingIDs is set.
+const HeadingIDFunc = "anchorize"
+
+// HeadingIDs generates heading IDs while a template runs, with the same
+// rules as goldmark's auto heading IDs. Each ID it returns is unique among
+// the IDs it has returned before, so a template needs a new HeadingIDs each
+// time it is executed.
+//
+// With WithActionHeadingIDs every auto heading ID is generated by a
+// HeadingIDs. Explicit IDs, such as {#intro}, are written as they are, and
+// HeadingIDs does not know about them.
+type HeadingIDs struct {
+	ids gparser.IDs
+}
+
+// NewHeadingIDs returns a HeadingIDs that has not returned any IDs yet.
+func NewHeadingIDs() *HeadingIDs {
+	return &HeadingIDs{ids: gparser.NewContext().IDs()}
+}
+
+// Anchorize returns the ID of a heading made of parts, the static text and
+// action values of its source.
+func (h *HeadingIDs) Anchorize(parts ...any) string {
+	var b strings.Builder
+	for _, part := range parts {
+		fmt.Fprint(&b, part)
+	}
+	return string(h.ids.Generate([]byte(b.String()), gast.KindHeading))
+}
+
+// FuncMap returns the function map that provides Anchorize to templates
+// converted with WithActionHeadingIDs.
+//
+//	tmpl, err := base.Clone()
+//	if err != nil {
+//		return err
+//	}
+//	return tmpl.Funcs(goldmarktemplate.NewHeadingIDs().FuncMap()).Execute(w, data)
+func (h *HeadingIDs) FuncMap() template.FuncMap {
+	return template.FuncMap{HeadingIDFunc: h.Anchorize}
+}
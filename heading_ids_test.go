@@ -0,0 +1,117 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestActionHeadingIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		executed string
+	}{
+		{
+			name:     "single action",
+			input:    "# {{ .Title }}\n\n## {{ .Title }}",
+			expected: "<h1 id=\"{{ anchorize .Title }}\">{{ .Title }}</h1>\n<h2 id=\"{{ anchorize .Title }}\">{{ .Title }}</h2>",
+			executed: "<h1 id=\"hello-world\">Hello, World</h1>\n<h2 id=\"hello-world-1\">Hello, World</h2>",
+		},
+		{
+			name:     "text and pipelines",
+			input:    "Intro to {{ .Title | printf \"%s!\" }} ({{ len .Items }})\n---",
+			expected: "<h2 id=\"{{ anchorize \"Intro to \" (.Title | printf \"%s!\") \" (\" (len .Items) \")\" }}\">Intro to {{ .Title | printf \"%s!\" }} ({{ len .Items }})</h2>",
+			executed: "<h2 id=\"intro-to-hello-world-3\">Intro to Hello, World! (3)</h2>",
+		},
+		{
+			name:     "trim markers and comments",
+			input:    "# A   {{- .Title -}}   B {{/* note */}}",
+			expected: "<h1 id=\"{{ anchorize \"A\" .Title \"B \" }}\">A   {{- .Title -}}   B {{/* note */}}</h1>",
+			executed: "<h1 id=\"ahello-worldb\">AHello, WorldB </h1>",
+		},
+		{
+			name:     "headings without printed actions",
+			input:    "# Static Title\n\n# {{ if .Draft }}Draft{{ end }}",
+			expected: "<h1 id=\"{{ anchorize \"Static Title\" }}\">Static Title</h1>\n<h1 id=\"{{ anchorize \"{{ if .Draft }}Draft{{ end }}\" }}\">{{ if .Draft }}Draft{{ end }}</h1>",
+			executed: "<h1 id=\"static-title\">Static Title</h1>\n<h1 id=\"-if-draft-draft-end-\"></h1>",
+		},
+		{
+			name:     "static heading with the same id as an action heading",
+			input:    "# {{ .Title }}\n\n# Hello World",
+			expected: "<h1 id=\"{{ anchorize .Title }}\">{{ .Title }}</h1>\n<h1 id=\"{{ anchorize \"Hello World\" }}\">Hello World</h1>",
+			executed: "<h1 id=\"hello-world\">Hello, World</h1>\n<h1 id=\"hello-world-1\">Hello World</h1>",
+		},
+		{
+			name:     "explicit id",
+			input:    "# {{ .Title }} {#intro}",
+			expected: "<h1 id=\"intro\">{{ .Title }}</h1>",
+			executed: "<h1 id=\"intro\">Hello, World</h1>",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(
+			WithActionHeadingIDs(),
+			ParserOptions(parser.WithAttribute()),
+		)),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	data := map[string]any{"Title": "Hello, World", "Items": []int{1, 2, 3}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+
+			tmpl, err := template.New("page").Funcs(NewHeadingIDs().FuncMap()).Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, data); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.executed {
+				t.Errorf("Executed mismatch\nExpected: %q\nGot:      %q", tt.executed, out.String())
+			}
+		})
+	}
+}
+
+func TestHeadingIDsMatchGoldmark(t *testing.T) {
+	titles := []string{"Hello, World", "Hello, World", "  Spaces  ", "under_score-dash", "Ünïcödé 日本", "!!!", "", "v1.2 (beta)"}
+
+	md := goldmark.New(goldmark.WithParserOptions(parser.WithAutoHeadingID()))
+	var source bytes.Buffer
+	for _, title := range titles {
+		source.WriteString("# " + title + "\n\n")
+	}
+	var buf bytes.Buffer
+	if err := md.Convert(source.Bytes(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := NewHeadingIDs()
+	for _, title := range titles {
+		id := ids.Anchorize(title)
+		if !strings.Contains(buf.String(), `id="`+id+`"`) {
+			t.Errorf("Anchorize(%q) = %q, not in goldmark output %q", title, id, buf.String())
+		}
+	}
+	if again := ids.Anchorize("Hello", ", World"); again != "hello-world-2" {
+		t.Errorf("Anchorize of parts = %q, want %q", again, "hello-world-2")
+	}
+}
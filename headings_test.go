@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
 )
 
@@ -157,6 +158,57 @@ func TestSetextHeadings(t *testing.T) {
 	}
 }
 
+func TestSetextHeadingAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "id attribute with template action",
+			input:    "Heading {id=\"{{ .ID }}\"}\n=======",
+			expected: `<h1 id="{{ .ID }}">Heading</h1>`,
+		},
+		{
+			name:     "attributes on the last line",
+			input:    "{{ .Title }}\nand more {class=\"{{ .Class }}\" data-x=\"a\"}\n---",
+			expected: "<h2 class=\"{{ .Class }}\" data-x=\"a\" id=\"and-more\">{{ .Title }}\nand more</h2>",
+		},
+		{
+			name:     "auto heading ID",
+			input:    "Getting Started\n===\n\nGetting Started\n---",
+			expected: "<h1 id=\"getting-started\">Getting Started</h1>\n<h2 id=\"getting-started-1\">Getting Started</h2>",
+		},
+		{
+			name:     "template id is not auto generated",
+			input:    "{{ .Title }} {id=\"{{ .Slug }}\"}\n===\n\n# Title",
+			expected: "<h1 id=\"{{ .Slug }}\">{{ .Title }}</h1>\n<h1 id=\"title\">Title</h1>",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(WithParserOptions(
+			parser.WithAttribute(),
+			parser.WithAutoHeadingID(),
+		)),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestHeadingsWithSpecialCharacters(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -106,8 +106,13 @@ func TestSoftLineBreaks(t *testing.T) {
 		},
 		{
 			name:     "template with internal newlines preserved",
+			input:    "{{ .Greeting }}\n{{ .Name }}\n{{ .Farewell }}",
+			expected: "<p>{{ .Greeting }}\n{{ .Name }}\n{{ .Farewell }}</p>",
+		},
+		{
+			name:     "block-level range is not wrapped in a paragraph",
 			input:    "{{ range .Items }}\n{{ .Name }}\n{{ end }}",
-			expected: "<p>{{ range .Items }}\n{{ .Name }}\n{{ end }}</p>",
+			expected: "{{ range .Items }}\n<p>{{ .Name }}</p>\n{{ end }}",
 		},
 	}
 
@@ -0,0 +1,109 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestLinkAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		executed string
+	}{
+		{
+			name:     "image",
+			input:    "![logo]({{ .Logo }}){width=\"{{ .W }}\" loading=\"lazy\"}",
+			expected: `<p><img src="{{ .Logo }}" alt="logo" width="{{ .W }}" loading="lazy"></p>`,
+			executed: `<p><img src="/logo.png" alt="logo" width="120" loading="lazy"></p>`,
+		},
+		{
+			name:     "link",
+			input:    "[Buy]({{ .URL }}){.btn data-sku=\"{{ .SKU }}\"}",
+			expected: `<p><a href="{{ .URL }}" class="btn" data-sku="{{ .SKU }}">Buy</a></p>`,
+			executed: `<p><a href="/buy?id=1&amp;x=2" class="btn" data-sku="A&amp;B">Buy</a></p>`,
+		},
+		{
+			name:     "reference and shortcut links",
+			input:    "[Buy][shop]{#buy} [shop]{target=_blank}\n\n[shop]: /shop",
+			expected: `<p><a href="/shop" id="buy">Buy</a> <a href="/shop" target="_blank">shop</a></p>`,
+		},
+		{
+			name:     "title attribute replaces the title",
+			input:    "[x](/y \"t\"){title=\"{{ .T }}\"} ![a](/a \"t\"){title=u}",
+			expected: `<p><a href="/y" title="{{ .T }}">x</a> <img src="/a" alt="a" title="u"></p>`,
+		},
+		{
+			name:     "URL attributes",
+			input:    "![a](/a){usemap=\"#m a\" data-url=\"/e f\"}",
+			expected: `<p><img src="/a" alt="a" usemap="#m%20a" data-url="/e f"></p>`,
+		},
+		{
+			name:     "filtered attributes",
+			input:    "[a](/a){href=\"/evil\" onclick=\"x()\" rel=\"{{ .Rel }}\"}",
+			expected: `<p><a href="/a" rel="{{ .Rel }}">a</a></p>`,
+		},
+		{
+			name:     "action after a link",
+			input:    "[a](/a){{ .Price }}",
+			expected: `<p><a href="/a">a</a>{{ .Price }}</p>`,
+		},
+		{
+			name:     "space before the list",
+			input:    "[a](/a) {.btn}",
+			expected: `<p><a href="/a">a</a> {.btn}</p>`,
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(WithLinkAttributes())),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	data := map[string]string{"Logo": "/logo.png", "W": "120", "URL": "/buy?id=1&x=2", "SKU": "A&B"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+
+			if tt.executed == "" {
+				return
+			}
+			tmpl, err := template.New("page").Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, data); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.executed {
+				t.Errorf("Executed mismatch\nExpected: %q\nGot:      %q", tt.executed, out.String())
+			}
+		})
+	}
+}
+
+func TestLinkAttributesAreOptIn(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(New()))
+	var buf bytes.Buffer
+	if err := md.Convert([]byte("[a](/a){.btn}"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<p><a href=\"/a\">a</a>{.btn}</p>\n"
+	if buf.String() != expected {
+		t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,101 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestActionsInLinkLabelsAndTitles(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		executed string
+	}{
+		{
+			name:     "bracket in label action",
+			input:    `[{{ index .Labels "]" }}](/x)`,
+			expected: `<p><a href="/x">{{ index .Labels "]" }}</a></p>`,
+			executed: `<p><a href="/x">close</a></p>`,
+		},
+		{
+			name:     "double quote in title action",
+			input:    `[x](/y "{{ printf "%q" .T }}")`,
+			expected: `<p><a href="/y" title="{{ printf "%q" .T }}">x</a></p>`,
+			executed: `<p><a href="/y" title="&#34;a&#34;">x</a></p>`,
+		},
+		{
+			name:     "single quote in title action",
+			input:    `[x](/y '{{ printf "'%s'" .T }}')`,
+			expected: `<p><a href="/y" title="{{ printf "'%s'" .T }}">x</a></p>`,
+			executed: `<p><a href="/y" title="&#39;a&#39;">x</a></p>`,
+		},
+		{
+			name:     "parenthesis in title action",
+			input:    `[x](/y ({{ printf "(%s)" .T }}))`,
+			expected: `<p><a href="/y" title="{{ printf "(%s)" .T }}">x</a></p>`,
+			executed: `<p><a href="/y" title="(a)">x</a></p>`,
+		},
+		{
+			name:     "bracket in reference label action",
+			input:    "[x][{{ \"]\" }}]\n\n[{{ \"]\" }}]: /y",
+			expected: `<p><a href="/y">x</a></p>`,
+		},
+		{
+			name:     "shortcut reference with an action",
+			input:    "[{{ \"]\" }}]\n\n[{{ \"]\" }}]: /y",
+			expected: `<p><a href="/y">{{ "]" }}</a></p>`,
+			executed: `<p><a href="/y">]</a></p>`,
+		},
+		{
+			name:     "quote in reference definition title action",
+			input:    "[x]\n\n[x]: /y \"{{ printf \"%q\" .T }}\"",
+			expected: `<p><a href="/y" title="{{ printf "%q" .T }}">x</a></p>`,
+			executed: `<p><a href="/y" title="&#34;a&#34;">x</a></p>`,
+		},
+		{
+			name:     "escaped closer outside actions",
+			input:    `[x](/y "a \" {{ .T }}")`,
+			expected: `<p><a href="/y" title="a \&quot; {{ .T }}">x</a></p>`,
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	data := map[string]any{"Labels": map[string]string{"]": "close"}, "T": "a"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+
+			if tt.executed == "" {
+				return
+			}
+			tmpl, err := template.New("page").Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, data); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.executed {
+				t.Errorf("Executed mismatch\nExpected: %q\nGot:      %q", tt.executed, out.String())
+			}
+		})
+	}
+}
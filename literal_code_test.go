@@ -0,0 +1,144 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+)
+
+func TestLiteralCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		input    string
+		expected string
+		executed string
+	}{
+		{
+			name:     "code span",
+			opts:     []Option{WithLiteralCode()},
+			input:    "Print `{{ .Name }}` as {{ .Name }}",
+			expected: `<p>Print <code>{{"{{ .Name }}"}}</code> as {{ .Name }}</p>`,
+			executed: "<p>Print <code>{{ .Name }}</code> as Ada</p>",
+		},
+		{
+			name:     "fenced and indented code blocks",
+			opts:     []Option{WithLiteralCode()},
+			input:    "```gotemplate\n{{ range .Items }}<li>{{ . }}</li>{{ end }}\n```\n\n    {{ .Name }}",
+			expected: "<pre><code class=\"language-gotemplate\">{{\"{{ range .Items }}\"}}&lt;li&gt;{{\"{{ . }}\"}}&lt;/li&gt;{{\"{{ end }}\"}}\n</code></pre>\n<pre><code>{{\"{{ .Name }}\"}}\n</code></pre>",
+			executed: "<pre><code class=\"language-gotemplate\">{{ range .Items }}&lt;li&gt;{{ . }}&lt;/li&gt;{{ end }}\n</code></pre>\n<pre><code>{{ .Name }}\n</code></pre>",
+		},
+		{
+			name:     "literal languages",
+			opts:     []Option{WithLiteralLanguages("gotemplate")},
+			input:    "```gotemplate\n{{ .Name }}\n```\n\n```text\n{{ .Name }}\n```\n\n`{{ .Name }}`",
+			expected: "<pre><code class=\"language-gotemplate\">{{\"{{ .Name }}\"}}\n</code></pre>\n<pre><code class=\"language-text\">{{ .Name }}\n</code></pre>\n<p><code>{{ .Name }}</code></p>",
+			executed: "<pre><code class=\"language-gotemplate\">{{ .Name }}\n</code></pre>\n<pre><code class=\"language-text\">Ada\n</code></pre>\n<p><code>Ada</code></p>",
+		},
+		{
+			name:     "literal attribute",
+			opts:     []Option{ParserOptions(parser.WithAttribute())},
+			input:    "```go\n{{ .Name }}\n```\n{: literal=true}",
+			expected: "<pre><code class=\"language-go\">{{\"{{ .Name }}\"}}\n</code></pre>",
+			executed: "<pre><code class=\"language-go\">{{ .Name }}\n</code></pre>",
+		},
+		{
+			name:     "literal attribute in the info string",
+			opts:     []Option{ParserOptions(parser.WithAttribute())},
+			input:    "```go {literal=true}\n{{ .Name }}\n```",
+			expected: "<pre><code class=\"language-go\">{{\"{{ .Name }}\"}}\n</code></pre>",
+			executed: "<pre><code class=\"language-go\">{{ .Name }}\n</code></pre>",
+		},
+		{
+			name:     "literal attribute as a string",
+			opts:     []Option{ParserOptions(parser.WithAttribute())},
+			input:    "```go {literal=\"true\"}\n{{ .Name }}\n```",
+			expected: "<pre><code class=\"language-go\">{{\"{{ .Name }}\"}}\n</code></pre>",
+		},
+		{
+			name:     "literal=false overrides the language",
+			opts:     []Option{WithLiteralLanguages("gotemplate"), ParserOptions(parser.WithAttribute())},
+			input:    "```gotemplate {literal=false}\n{{ .Name }}\n```",
+			expected: "<pre><code class=\"language-gotemplate\">{{ .Name }}\n</code></pre>",
+			executed: "<pre><code class=\"language-gotemplate\">Ada\n</code></pre>",
+		},
+		{
+			name:     "bare literal is not an attribute",
+			opts:     []Option{ParserOptions(parser.WithAttribute())},
+			input:    "```go {literal}\n{{ .Name }}\n```",
+			expected: "<pre><code class=\"language-go\">{{ .Name }}\n</code></pre>",
+			executed: "<pre><code class=\"language-go\">Ada\n</code></pre>",
+		},
+		{
+			name: "code spans literal beside denied code blocks",
+			opts: []Option{
+				WithActionPolicy(ast.ActionPolicy{ast.ContextCodeBlock: ast.PolicyDeny}),
+				WithLiteralCode(),
+			},
+			input:    "`{{ .Name }}`",
+			expected: `<p><code>{{"{{ .Name }}"}}</code></p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := goldmark.New(goldmark.WithExtensions(New(tt.opts...)))
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+
+			if tt.executed == "" {
+				return
+			}
+			tmpl, err := template.New("page").Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, map[string]string{"Name": "Ada"}); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.executed {
+				t.Errorf("Executed mismatch\nExpected: %q\nGot:      %q", tt.executed, out.String())
+			}
+		})
+	}
+}
+
+func TestLiteralCodeDenied(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(New(
+		WithActionPolicy(ast.ActionPolicy{ast.ContextCodeBlock: ast.PolicyDeny}),
+		WithLiteralCode(),
+	)))
+	var buf bytes.Buffer
+	err := md.Convert([]byte("```\n{{ .Name }}\n```"), &buf)
+	expected := "2:1: {{ .Name }}: actions are not allowed in code blocks"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestLiteralFenceNotDenied(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(New(
+		WithActionPolicy(ast.ActionPolicy{ast.ContextCodeBlock: ast.PolicyDeny}),
+		WithLiteralLanguages("gotemplate"),
+		ParserOptions(parser.WithAttribute()),
+	)))
+	var buf bytes.Buffer
+	input := "```gotemplate\n{{ .Name }}\n```\n\n```go {literal=true}\n{{ .Name }}\n```\n\n```go {literal=false}\n{{ .Name }}\n```"
+	err := md.Convert([]byte(input), &buf)
+	expected := "10:1: {{ .Name }}: actions are not allowed in code blocks"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,92 @@
+package goldmarktemplate
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"text/template/parse"
+
+	"github.com/yuin/goldmark"
+)
+
+// ParseFS converts the Markdown files in fsys that match the patterns and
+// parses them into a single template set, like template.ParseFS. Each file
+// is named by its path in fsys, such as "partials/footer.md", so the files
+// can call each other with {{ template "partials/footer.md" . }}. The
+// returned template is the one for the first file.
+//
+// The patterns follow the semantics of fs.Glob, and every pattern must
+// match at least one file. A file matched by several patterns is parsed
+// once. It is an error for a file to be named like a template defined with
+// {{ define }}, in the same file or another one.
+func ParseFS(fsys fs.FS, patterns ...string) (*template.Template, error) {
+	return ParseFSInto(nil, fsys, nil, patterns...)
+}
+
+// ParseFSInto is like ParseFS but adds the templates to t, unless t is nil,
+// and converts them with opts as Compile does. Give t its function map
+// before calling ParseFSInto so that the files can use the functions.
+func ParseFSInto(t *template.Template, fsys fs.FS, opts []goldmark.Option, patterns ...string) (*template.Template, error) {
+	return parseFSInto(t, fsys, opts, patterns, nil)
+}
+
+// ParseFSInto is like the ParseFSInto function, and records the source map
+// of each file in m.
+func (m SourceMaps) ParseFSInto(t *template.Template, fsys fs.FS, opts []goldmark.Option, patterns ...string) (*template.Template, error) {
+	return parseFSInto(t, fsys, opts, patterns, m)
+}
+
+func parseFSInto(t *template.Template, fsys fs.FS, opts []goldmark.Option, patterns []string, maps SourceMaps) (*template.Template, error) {
+	var filenames []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		list, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("template: pattern matches no files: %#q", pattern)
+		}
+		for _, filename := range list {
+			if !seen[filename] {
+				seen[filename] = true
+				filenames = append(filenames, filename)
+			}
+		}
+	}
+	if len(filenames) == 0 {
+		return nil, errors.New("template: no files named in call to ParseFS")
+	}
+
+	c := newCompiler(opts)
+	// The trees of the files parsed so far, to notice a file that replaces
+	// one of them with a {{ define }}.
+	trees := map[string]*parse.Tree{}
+	for _, filename := range filenames {
+		source, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			t = template.New(filename)
+		}
+		if defined := t.Lookup(filename); defined != nil && defined.Tree != nil {
+			return nil, fmt.Errorf("template: %s: file name is already the name of a defined template", filename)
+		}
+		tmpl := t
+		if filename != t.Name() {
+			tmpl = t.New(filename)
+		}
+		if _, err := c.compile(tmpl, filename, source, maps); err != nil {
+			return nil, err
+		}
+		trees[filename] = tmpl.Tree
+		for name, tree := range trees {
+			if t.Lookup(name).Tree != tree {
+				return nil, fmt.Errorf("template: %s: redefinition of template %q", filename, name)
+			}
+		}
+	}
+	return t, nil
+}
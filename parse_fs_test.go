@@ -0,0 +1,122 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/index.md":      {Data: []byte("# {{ .Title }}\n\n{{ template \"partials/footer.md\" . }}")},
+		"pages/about.md":      {Data: []byte("About {{ .Title }}")},
+		"partials/footer.md":  {Data: []byte("Made by [{{ .Author }}]({{ .Site }})")},
+		"partials/readme.txt": {Data: []byte("not a template")},
+	}
+
+	tmpl, err := ParseFS(fsys, "pages/*.md", "partials/*.md")
+	if err != nil {
+		t.Fatalf("ParseFS returned error: %v", err)
+	}
+
+	var names []string
+	for _, tt := range tmpl.Templates() {
+		names = append(names, tt.Name())
+	}
+	for _, name := range []string{"pages/index.md", "pages/about.md", "partials/footer.md"} {
+		if tmpl.Lookup(name) == nil {
+			t.Errorf("template %q not found in %q", name, names)
+		}
+	}
+	if tmpl.Lookup("partials/readme.txt") != nil {
+		t.Errorf("unmatched file was parsed")
+	}
+	if tmpl.Name() != "pages/about.md" {
+		t.Errorf("expected the first matched file to name the set, got %q", tmpl.Name())
+	}
+
+	var out bytes.Buffer
+	data := map[string]string{"Title": "Home", "Author": "Ann", "Site": "https://example.com"}
+	if err := tmpl.ExecuteTemplate(&out, "pages/index.md", data); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	expected := "<h1>Home</h1>\n<p>Made by <a href=\"https://example.com\">Ann</a></p>\n\n"
+	if out.String() != expected {
+		t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", expected, out.String())
+	}
+}
+
+func TestParseFSErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.md":         {Data: []byte("{{ .A }}")},
+		"bad.md":       {Data: []byte("Intro\n\nText {{ .A ( }}")},
+		"defines/1.md": {Data: []byte("{{ define \"defines/2.md\" }}x{{ end }}")},
+		"defines/2.md": {Data: []byte("Two")},
+		"defines/3.md": {Data: []byte("{{ define \"defines/2.md\" }}x{{ end }}")},
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected string
+	}{
+		{name: "no patterns", patterns: nil, expected: "template: no files named in call to ParseFS"},
+		{name: "pattern matches no files", patterns: []string{"*.html"}, expected: "template: pattern matches no files: `*.html`"},
+		{name: "malformed pattern", patterns: []string{"[a"}, expected: "syntax error in pattern"},
+		{name: "parse error names the file", patterns: []string{"*.md"}, expected: "template: bad.md:3:6: unclosed left paren"},
+		{name: "file named like a define", patterns: []string{"defines/1.md", "defines/2.md"}, expected: "template: defines/2.md: file name is already the name of a defined template"},
+		{name: "define named like a file", patterns: []string{"defines/2.md", "defines/3.md"}, expected: "template: defines/3.md: redefinition of template \"defines/2.md\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFS(fsys, tt.patterns...)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseFSOverlappingPatterns(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.md": {Data: []byte("{{ define \"x\" }}A{{ end }}{{ template \"x\" }}")},
+		"b.md": {Data: []byte("B")},
+	}
+
+	tmpl, err := ParseFS(fsys, "*.md", "a.md")
+	if err != nil {
+		t.Fatalf("ParseFS returned error: %v", err)
+	}
+	if len(tmpl.Templates()) != 3 {
+		t.Errorf("expected 3 templates, got %d", len(tmpl.Templates()))
+	}
+}
+
+func TestParseFSInto(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.md": {Data: []byte("Hello [[ .Name | shout ]]")},
+	}
+	base := template.New("layout").Funcs(template.FuncMap{"shout": strings.ToUpper})
+	template.Must(base.Parse(`<main>{{ template "page.md" . }}</main>`))
+
+	tmpl, err := ParseFSInto(base, fsys, []goldmark.Option{
+		goldmark.WithExtensions(New(WithDelims("[[", "]]"))),
+	}, "*.md")
+	if err != nil {
+		t.Fatalf("ParseFSInto returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, map[string]string{"Name": "ann"}); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	expected := "<main><p>Hello ANN</p>\n</main>"
+	if out.String() != expected {
+		t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", expected, out.String())
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"io"
 	"strconv"
 
+	"github.com/hermit-ink/goldmark-template/ast"
 	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 )
@@ -17,6 +19,10 @@ var attrNameClass = []byte("class")
 type Attribute struct {
 	Name  []byte
 	Value interface{}
+
+	// segment is where in the source of the reader Value was copied from,
+	// if Value is a verbatim copy of it.
+	segment text.Segment
 }
 
 // An Attributes is a collection of attributes.
@@ -36,6 +42,7 @@ func (as Attributes) findUpdate(name []byte, cb func(v interface{}) interface{})
 	for i, a := range as {
 		if bytes.Equal(a.Name, name) {
 			as[i].Value = cb(a.Value)
+			as[i].segment = text.Segment{}
 			return true
 		}
 	}
@@ -46,6 +53,10 @@ func (as Attributes) findUpdate(name []byte, cb func(v interface{}) interface{})
 // ParseAttributes returns a parsed attributes and true if could parse
 // attributes, otherwise nil and false.
 func ParseAttributes(reader text.Reader) (Attributes, bool) {
+	return parseAttributes(reader, tutil.DefaultDelimiters)
+}
+
+func parseAttributes(reader text.Reader, delims tutil.Delimiters) (Attributes, bool) {
 	savedLine, savedPosition := reader.Position()
 	reader.SkipSpaces()
 	if reader.Peek() != '{' {
@@ -53,15 +64,25 @@ func ParseAttributes(reader text.Reader) (Attributes, bool) {
 		return nil, false
 	}
 	reader.Advance(1)
+	attrs, ok := parseAttributeList(reader, delims)
+	if !ok {
+		reader.SetPosition(savedLine, savedPosition)
+		return nil, false
+	}
+	return attrs, true
+}
+
+// parseAttributeList parses the attributes after the opening brace of an
+// attribute list, up to and including its closing brace.
+func parseAttributeList(reader text.Reader, delims tutil.Delimiters) (Attributes, bool) {
 	attrs := Attributes{}
 	for {
 		if reader.Peek() == '}' {
 			reader.Advance(1)
 			return attrs, true
 		}
-		attr, ok := parseAttribute(reader)
+		attr, ok := parseAttribute(reader, delims)
 		if !ok {
-			reader.SetPosition(savedLine, savedPosition)
 			return nil, false
 		}
 		if bytes.Equal(attr.Name, attrNameClass) {
@@ -83,31 +104,33 @@ func ParseAttributes(reader text.Reader) (Attributes, bool) {
 	}
 }
 
-func parseAttribute(reader text.Reader) (Attribute, bool) {
+func parseAttribute(reader text.Reader, delims tutil.Delimiters) (Attribute, bool) {
 	reader.SkipSpaces()
 	c := reader.Peek()
 	if c == '#' || c == '.' {
 		reader.Advance(1)
-		line, _ := reader.PeekLine()
-		i := 0
+		line, segment := reader.PeekLine()
 		// HTML5 allows any kind of characters as id, but XHTML restricts characters for id.
 		// CommonMark is basically defined for XHTML(even though it is legacy).
-		// So we restrict id characters.
-		for ; i < len(line) && !util.IsSpace(line[i]) &&
-			(!util.IsPunct(line[i]) || line[i] == '_' ||
-				line[i] == '-' || line[i] == ':' || line[i] == '.'); i++ {
-		}
+		// So we restrict id characters, except in actions such as #{{ .ID }}.
+		i := scanAttributeWord(line, delims, func(c byte) bool {
+			return !util.IsSpace(c) &&
+				(!util.IsPunct(c) || c == '_' || c == '-' || c == ':' || c == '.')
+		})
 		name := attrNameClass
 		if c == '#' {
 			name = attrNameID
 		}
 		reader.Advance(i)
-		return Attribute{Name: name, Value: line[0:i]}, true
+		return Attribute{Name: name, Value: line[0:i], segment: lineSegment(segment, 0, i)}, true
 	}
 	line, _ := reader.PeekLine()
 	if len(line) == 0 {
 		return Attribute{}, false
 	}
+	if bytes.HasPrefix(line, delims.Left) {
+		return parseBareAttribute(reader, delims)
+	}
 	c = line[0]
 	if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
 		c == '_' || c == ':') {
@@ -131,7 +154,7 @@ func parseAttribute(reader text.Reader) (Attribute, bool) {
 	}
 	reader.Advance(1)
 	reader.SkipSpaces()
-	value, ok := parseAttributeValue(reader)
+	value, segment, ok := parseAttributeValue(reader, delims)
 	if !ok {
 		return Attribute{}, false
 	}
@@ -140,37 +163,43 @@ func parseAttribute(reader text.Reader) (Attribute, bool) {
 			return Attribute{}, false
 		}
 	}
-	return Attribute{Name: name, Value: value}, true
+	return Attribute{Name: name, Value: value, segment: segment}, true
 }
 
-func parseAttributeValue(reader text.Reader) (interface{}, bool) {
+// parseAttributeValue parses an attribute value. For a string value that is
+// a verbatim copy of the source, it also returns where it was copied from.
+func parseAttributeValue(reader text.Reader, delims tutil.Delimiters) (interface{}, text.Segment, bool) {
 	reader.SkipSpaces()
+	if line, _ := reader.PeekLine(); bytes.HasPrefix(line, delims.Left) {
+		return parseAttributeOthers(reader, delims)
+	}
 	c := reader.Peek()
 	var value interface{}
+	var segment text.Segment
 	var ok bool
 	switch c {
 	case text.EOF:
-		return Attribute{}, false
+		return Attribute{}, segment, false
 	case '{':
-		value, ok = ParseAttributes(reader)
+		value, ok = parseAttributes(reader, delims)
 	case '[':
-		value, ok = parseAttributeArray(reader)
+		value, ok = parseAttributeArray(reader, delims)
 	case '"':
-		value, ok = parseAttributeString(reader)
+		value, segment, ok = parseAttributeString(reader, delims)
 	default:
 		if c == '-' || c == '+' || util.IsNumeric(c) {
 			value, ok = parseAttributeNumber(reader)
 		} else {
-			value, ok = parseAttributeOthers(reader)
+			value, segment, ok = parseAttributeOthers(reader, delims)
 		}
 	}
 	if !ok {
-		return nil, false
+		return nil, text.Segment{}, false
 	}
-	return value, true
+	return value, segment, true
 }
 
-func parseAttributeArray(reader text.Reader) ([]interface{}, bool) {
+func parseAttributeArray(reader text.Reader, delims tutil.Delimiters) ([]interface{}, bool) {
 	reader.Advance(1) // skip [
 	ret := []interface{}{}
 	for i := 0; ; i++ {
@@ -188,7 +217,7 @@ func parseAttributeArray(reader text.Reader) ([]interface{}, bool) {
 			return nil, false
 		}
 		reader.SkipSpaces()
-		value, ok := parseAttributeValue(reader)
+		value, _, ok := parseAttributeValue(reader, delims)
 		if !ok {
 			return nil, false
 		}
@@ -197,19 +226,21 @@ func parseAttributeArray(reader text.Reader) ([]interface{}, bool) {
 	}
 }
 
-func parseAttributeString(reader text.Reader) ([]byte, bool) {
+func parseAttributeString(reader text.Reader, delims tutil.Delimiters) ([]byte, text.Segment, bool) {
 	reader.Advance(1) // skip "
-	line, _ := reader.PeekLine()
+	line, segment := reader.PeekLine()
 	i := 0
 	l := len(line)
 	var buf bytes.Buffer
-	actionTracker := tutil.NewActionState()
+	escaped := false
+	actionTracker := delims.NewActionState()
 	
 	for i < l {
 		c := line[i]
 		actionTracker.ProcessChar(line, i)
 		
 		if c == '\\' && i != l-1 && !actionTracker.InAction() {
+			escaped = true
 			n := line[i+1]
 			switch n {
 			case '"', '/', '\\':
@@ -238,12 +269,15 @@ func parseAttributeString(reader text.Reader) ([]byte, bool) {
 		}
 		if c == '"' && !actionTracker.InAction() {
 			reader.Advance(i + 1)
-			return buf.Bytes(), true
+			if escaped {
+				return buf.Bytes(), text.Segment{}, true
+			}
+			return buf.Bytes(), lineSegment(segment, 0, i), true
 		}
 		buf.WriteByte(c)
 		i++
 	}
-	return nil, false
+	return nil, text.Segment{}, false
 }
 
 func scanAttributeDecimal(reader text.Reader, w io.ByteWriter) {
@@ -303,47 +337,130 @@ var bytesTrue = []byte("true")
 var bytesFalse = []byte("false")
 var bytesNull = []byte("null")
 
-func parseAttributeOthers(reader text.Reader) (interface{}, bool) {
-	line, _ := reader.PeekLine()
+func parseAttributeOthers(reader text.Reader, delims tutil.Delimiters) (interface{}, text.Segment, bool) {
+	line, segment := reader.PeekLine()
 	c := line[0]
 	if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
-		c == '_' || c == ':') {
-		return nil, false
-	}
-	i := 0
-	actionTracker := tutil.NewActionState()
-	
-	for ; i < len(line); i++ {
-		c := line[i]
-		actionTracker.ProcessChar(line, i)
-		
-		// If we're not in a template action, apply normal character restrictions
-		if !actionTracker.InAction() {
-			if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
-				(c >= '0' && c <= '9') ||
-				c == '_' || c == ':' || c == '.' || c == '-') {
-				break
-			}
-		}
+		c == '_' || c == ':') && !bytes.HasPrefix(line, delims.Left) {
+		return nil, text.Segment{}, false
 	}
-	
+	i := scanAttributeWord(line, delims, func(c byte) bool {
+		return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9') ||
+			c == '_' || c == ':' || c == '.' || c == '-'
+	})
+
 	value := line[:i]
 	reader.Advance(i)
-	
+
 	// Templates are always valid, otherwise use original validation
-	if tutil.ContainsAction(value) {
-		return value, true
+	if delims.ContainsAction(value) {
+		return value, lineSegment(segment, 0, i), true
 	}
 	
 	// For non-template values, use original goldmark logic
 	if bytes.Equal(value, bytesTrue) {
-		return true, true
+		return true, text.Segment{}, true
 	}
 	if bytes.Equal(value, bytesFalse) {
-		return false, true
+		return false, text.Segment{}, true
 	}
 	if bytes.Equal(value, bytesNull) {
-		return nil, true
+		return nil, text.Segment{}, true
 	}
-	return value, true
-}
\ No newline at end of file
+	return value, lineSegment(segment, 0, i), true
+}
+
+// scanAttributeWord returns the length of the word at the start of line made
+// of the bytes that ok accepts and of whole actions, whatever they contain.
+func scanAttributeWord(line []byte, delims tutil.Delimiters, ok func(byte) bool) int {
+	i := 0
+	for i < len(line) {
+		if end := delims.FindActionEnd(line, i); end > 0 {
+			i = end
+			continue
+		}
+		if !ok(line[i]) {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+// parseBareAttribute parses an action that stands for whole attributes, such
+// as { {{ .Attrs }} } or { {{ if .Hidden }}hidden{{ end }} }. An action that
+// opens a block runs up to its matching {{ end }}, with the text and actions
+// in between, but not past the } that closes the list. The result is an
+// Attribute whose Name and Value are both the action, which renderers write
+// as it is instead of as name="value".
+func parseBareAttribute(reader text.Reader, delims tutil.Delimiters) (Attribute, bool) {
+	line, segment := reader.PeekLine()
+	i := 0
+	depth := 0
+	for {
+		end := delims.FindActionEnd(line, i)
+		if end < 0 {
+			return Attribute{}, false
+		}
+		action, _ := ast.ParseActionDelims(line[i:end], delims)
+		switch {
+		case action.Kind.OpensBlock():
+			depth++
+		case action.Kind == ast.ActionEnd:
+			depth--
+		}
+		i = end
+		if depth <= 0 {
+			break
+		}
+		next := bytes.Index(line[i:], delims.Left)
+		if next < 0 {
+			return Attribute{}, false
+		}
+		// The text between actions ends at the closing brace of the list.
+		if bytes.IndexByte(line[i:i+next], '}') >= 0 {
+			return Attribute{}, false
+		}
+		i += next
+	}
+	if depth < 0 {
+		return Attribute{}, false
+	}
+	reader.Advance(i)
+	return Attribute{Name: line[:i], Value: line[:i], segment: lineSegment(segment, 0, i)}, true
+}
+
+// lineSegment returns the segment of line[start:stop], where line and
+// segment are as returned by PeekLine.
+func lineSegment(segment text.Segment, start, stop int) text.Segment {
+	return text.NewSegment(segment.Start-segment.Padding+start, segment.Start-segment.Padding+stop)
+}
+
+var valueSegmentsKey = NewContextKey()
+
+// valueSegments returns the value segments of the document being parsed
+// with pc, which NewValueSegmentsAppender adds to the document once it is
+// parsed.
+func valueSegments(pc Context) *ast.ValueSegments {
+	v, _ := pc.Get(valueSegmentsKey).(*ast.ValueSegments)
+	if v == nil {
+		v = ast.NewValueSegments()
+		pc.Set(valueSegmentsKey, v)
+	}
+	return v
+}
+
+// setAttributes sets attrs on n and records where their values come from in
+// values. base is the offset in the document source of the start of the
+// source of the reader that attrs were parsed from.
+func setAttributes(values *ast.ValueSegments, n gast.Node, attrs Attributes, base int) {
+	for _, attr := range attrs {
+		n.SetAttribute(attr.Name, attr.Value)
+		segment := attr.segment
+		if !segment.IsEmpty() {
+			segment = text.NewSegment(segment.Start+base, segment.Stop+base)
+		}
+		values.Set(n, ast.AttributeField(attr.Name), segment)
+	}
+}
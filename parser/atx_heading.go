@@ -1,6 +1,7 @@
 package parser
 
 import (
+	tutil "github.com/hermit-ink/goldmark-template/util"
 	"github.com/yuin/goldmark/ast"
 	gparser "github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
@@ -24,6 +25,7 @@ var (
 type HeadingConfig struct {
 	AutoHeadingID bool
 	Attribute     bool
+	ActionConfig
 }
 
 // SetOption implements SetOptioner.
@@ -83,6 +85,7 @@ type atxHeadingParser struct {
 // NewATXHeadingParser return a new BlockParser that can parse ATX headings.
 func NewATXHeadingParser(opts ...HeadingOption) BlockParser {
 	p := &atxHeadingParser{}
+	p.ActionConfig = NewActionConfig()
 	for _, o := range opts {
 		o.SetHeadingOption(&p.HeadingConfig)
 	}
@@ -143,13 +146,11 @@ func (b *atxHeadingParser) Open(parent ast.Node, reader text.Reader, pc Context)
 		}
 		if closureClose > 0 {
 			reader.Advance(closureClose)
-			attrs, ok := ParseAttributes(reader)
+			attrs, ok := parseAttributes(reader, b.Delims)
 			rest, _ := reader.PeekLine()
 			parsed = ok && util.IsBlank(rest)
 			if parsed {
-				for _, attr := range attrs {
-					node.SetAttribute(attr.Name, attr.Value)
-				}
+				setAttributes(valueSegments(pc), node, attrs, 0)
 				node.Lines().Append(text.NewSegment(
 					segment.Start+start+1-segment.Padding,
 					segment.Start+closureOpen-segment.Padding))
@@ -187,14 +188,14 @@ func (b *atxHeadingParser) Close(node ast.Node, reader text.Reader, pc Context)
 	if b.Attribute {
 		_, ok := node.AttributeString("id")
 		if !ok {
-			parseLastLineAttributes(node, reader, pc)
+			parseLastLineAttributes(node, reader, pc, b.Delims)
 		}
 	}
 
 	if b.AutoHeadingID {
 		id, ok := node.AttributeString("id")
 		if !ok {
-			generateAutoHeadingID(node.(*ast.Heading), reader, pc)
+			generateHeadingID(node.(*ast.Heading), reader, pc, b.ActionConfig)
 		} else {
 			pc.IDs().Put(id.([]byte))
 		}
@@ -220,7 +221,7 @@ func generateAutoHeadingID(node *ast.Heading, reader text.Reader, pc Context) {
 	node.SetAttribute(attrNameID, headingID)
 }
 
-func parseLastLineAttributes(node ast.Node, reader text.Reader, pc Context) {
+func parseLastLineAttributes(node ast.Node, reader text.Reader, pc Context, delims tutil.Delimiters) {
 	lastIndex := node.Lines().Len() - 1
 	if lastIndex < 0 { // empty headings
 		return
@@ -247,7 +248,7 @@ func parseLastLineAttributes(node ast.Node, reader text.Reader, pc Context) {
 		}
 		if c == '{' {
 			sl, start = lr.Position()
-			attrs, ok = ParseAttributes(lr)
+			attrs, ok = parseAttributes(lr, delims)
 			_, end = lr.Position()
 			if !ok {
 				lr.SetPosition(sl, start)
@@ -258,9 +259,7 @@ func parseLastLineAttributes(node ast.Node, reader text.Reader, pc Context) {
 		}
 	}
 	if ok && util.IsBlank(line[end.Start:]) {
-		for _, attr := range attrs {
-			node.SetAttribute(attr.Name, attr.Value)
-		}
+		setAttributes(valueSegments(pc), node, attrs, lastLine.Start-lastLine.Padding)
 		lastLine.Stop = lastLine.Start + start.Start
 		node.Lines().Set(lastIndex, lastLine)
 	}
@@ -3,19 +3,23 @@ package parser
 import (
 	"bytes"
 
-	tutil "github.com/hermit-ink/goldmark-template/util"
+	tast "github.com/hermit-ink/goldmark-template/ast"
 	"github.com/yuin/goldmark/ast"
 	gparser "github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 )
 
-type autoLinkParser struct{}
+type autoLinkParser struct {
+	ActionConfig
+}
 
 // NewAutoLinkParser returns a new InlineParser that parses autolinks with Go
 // template action support
-func NewAutoLinkParser() gparser.InlineParser {
-	return &autoLinkParser{}
+func NewAutoLinkParser(opts ...ActionOption) gparser.InlineParser {
+	return &autoLinkParser{
+		ActionConfig: NewActionConfig(opts...),
+	}
 }
 
 func (s *autoLinkParser) Trigger() []byte {
@@ -39,21 +43,19 @@ func (s *autoLinkParser) Parse(parent ast.Node, block text.Reader, pc gparser.Co
 	// <{{ .URL }}>
 	// <{{> will also get treated like an autolink even though its not valid
 	// but that's ok
-	if len(urlContent) >= 2 && urlContent[0] == '{' && urlContent[1] == '{' {
+	if bytes.HasPrefix(urlContent, s.Delims.Left) {
 		stop := closePos + 1 // +1 for the '>'
-		value := ast.NewTextSegment(text.NewSegment(segment.Start+1, segment.Start+stop))
 		block.Advance(stop + 1)
-		return ast.NewAutoLink(ast.AutoLinkURL, value)
+		return newAutoLink(pc, ast.AutoLinkURL, text.NewSegment(segment.Start+1, segment.Start+stop))
 	}
 
 	// If it starts with a URL-like string (util.FindURLIndex) and it has a
 	// template action in it then construct an autolink ast node and return it
 	// <https://......{{.Something}}>
-	if util.FindURLIndex(urlContent) > 0 && tutil.ContainsAction(urlContent) {
+	if util.FindURLIndex(urlContent) > 0 && s.Delims.ContainsAction(urlContent) {
 		stop := closePos + 1 // +1 for the '>'
-		value := ast.NewTextSegment(text.NewSegment(segment.Start+1, segment.Start+stop))
 		block.Advance(stop + 1)
-		return ast.NewAutoLink(ast.AutoLinkURL, value)
+		return newAutoLink(pc, ast.AutoLinkURL, text.NewSegment(segment.Start+1, segment.Start+stop))
 	}
 
 	// Otherwise, use goldmark's original logic
@@ -70,7 +72,14 @@ func (s *autoLinkParser) Parse(parent ast.Node, block text.Reader, pc gparser.Co
 	if stop >= len(line) || line[stop] != '>' {
 		return nil
 	}
-	value := ast.NewTextSegment(text.NewSegment(segment.Start+1, segment.Start+stop))
 	block.Advance(stop + 1)
-	return ast.NewAutoLink(typ, value)
+	return newAutoLink(pc, typ, text.NewSegment(segment.Start+1, segment.Start+stop))
+}
+
+// newAutoLink returns an autolink whose label is the text at segment, and
+// records segment as where the label comes from.
+func newAutoLink(pc gparser.Context, typ ast.AutoLinkType, segment text.Segment) ast.Node {
+	n := ast.NewAutoLink(typ, ast.NewTextSegment(segment))
+	valueSegments(pc).Set(n, tast.FieldLabel, segment)
+	return n
 }
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"bytes"
+
+	gast "github.com/yuin/goldmark/ast"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// blockAttributeList is a kramdown block attribute list, such as
+// {: .note data-user="{{ .User.ID }}"}, until the blockAttributeAttacher
+// moves its attributes to the block before it.
+type blockAttributeList struct {
+	gast.BaseBlock
+	attrs Attributes
+}
+
+// Dump implements Node.Dump.
+func (n *blockAttributeList) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, nil, nil)
+}
+
+var kindBlockAttributeList = gast.NewNodeKind("BlockAttributeList")
+
+// Kind implements Node.Kind.
+func (n *blockAttributeList) Kind() gast.NodeKind {
+	return kindBlockAttributeList
+}
+
+// blockAttributeParser is a block parser for lines that hold a block
+// attribute list. It is enabled by the Attribute parser option.
+type blockAttributeParser struct {
+	ActionConfig
+	attribute bool
+}
+
+var blockAttributeListStart = []byte("{:")
+
+// NewBlockAttributeParser returns a new BlockParser that parses kramdown
+// block attribute lists, such as {: .note data-user="{{ .User.ID }}"}, on
+// the line after a paragraph, list, blockquote, code block or table. Their
+// attributes, actions included, are set on that block. Like goldmark's
+// heading attributes, the lists are only parsed with the Attribute parser
+// option.
+func NewBlockAttributeParser(opts ...ActionOption) BlockParser {
+	return &blockAttributeParser{
+		ActionConfig: NewActionConfig(opts...),
+	}
+}
+
+// SetOption implements SetOptioner.
+func (b *blockAttributeParser) SetOption(name OptionName, _ interface{}) {
+	if name == optAttribute {
+		b.attribute = true
+	}
+}
+
+func (b *blockAttributeParser) Trigger() []byte {
+	return []byte{'{'}
+}
+
+func (b *blockAttributeParser) Open(parent gast.Node, reader text.Reader, pc Context) (gast.Node, State) {
+	if !b.attribute || parent.LastChild() == nil {
+		return nil, NoChildren
+	}
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, NoChildren
+	}
+	line, _ := reader.PeekLine()
+	if !bytes.HasPrefix(line[pos:], blockAttributeListStart) {
+		return nil, NoChildren
+	}
+	savedLine, savedPosition := reader.Position()
+	reader.Advance(pos + len(blockAttributeListStart))
+	attrs, ok := parseAttributeList(reader, b.Delims)
+	rest, _ := reader.PeekLine()
+	if !ok || !util.IsBlank(rest) {
+		reader.SetPosition(savedLine, savedPosition)
+		return nil, NoChildren
+	}
+	return &blockAttributeList{attrs: attrs}, NoChildren
+}
+
+func (b *blockAttributeParser) Continue(node gast.Node, reader text.Reader, pc Context) State {
+	return Close
+}
+
+func (b *blockAttributeParser) Close(node gast.Node, reader text.Reader, pc Context) {
+}
+
+func (b *blockAttributeParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *blockAttributeParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// blockAttributeAttacher is an ASTTransformer that sets the attributes of
+// each block attribute list on the block before it.
+type blockAttributeAttacher struct {
+}
+
+// NewBlockAttributeAttacher returns a new ASTTransformer that moves the
+// attributes of the block attribute lists found by NewBlockAttributeParser to
+// the blocks they follow. It runs after the paragraph transformers, so a
+// list after a GFM table applies to the table.
+func NewBlockAttributeAttacher() gparser.ASTTransformer {
+	return &blockAttributeAttacher{}
+}
+
+func (t *blockAttributeAttacher) Transform(node *gast.Document, reader text.Reader, pc Context) {
+	var lists []*blockAttributeList
+	_ = gast.Walk(node, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if list, ok := n.(*blockAttributeList); ok && entering {
+			lists = append(lists, list)
+		}
+		return gast.WalkContinue, nil
+	})
+	values := valueSegments(pc)
+	for _, list := range lists {
+		if prev := list.PreviousSibling(); prev != nil {
+			setAttributes(values, prev, list.attrs, 0)
+		}
+		list.Parent().RemoveChild(list.Parent(), list)
+	}
+}
@@ -1,20 +1,20 @@
 package parser
 
 import (
-	tutil "github.com/hermit-ink/goldmark-template/util"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/text"
 )
 
 type codeSpanParser struct {
+	ActionConfig
 }
 
-var defaultCodeSpanParser = &codeSpanParser{}
-
 // NewCodeSpanParser return a new InlineParser that parses inline codes
 // surrounded by '`' with template action support.
-func NewCodeSpanParser() InlineParser {
-	return defaultCodeSpanParser
+func NewCodeSpanParser(opts ...ActionOption) InlineParser {
+	return &codeSpanParser{
+		ActionConfig: NewActionConfig(opts...),
+	}
 }
 
 func (s *codeSpanParser) Trigger() []byte {
@@ -31,7 +31,7 @@ func (s *codeSpanParser) Parse(parent ast.Node, block text.Reader, pc Context) a
 	node := ast.NewCodeSpan()
 
 	// Template action tracking
-	tracker := tutil.NewActionState()
+	tracker := s.Delims.NewActionState()
 
 	for {
 		line, segment := block.PeekLine()
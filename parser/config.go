@@ -0,0 +1,90 @@
+package parser
+
+import (
+	tutil "github.com/hermit-ink/goldmark-template/util"
+)
+
+// An ActionConfig struct holds the configuration shared by the action-aware
+// parsers.
+type ActionConfig struct {
+	// Delims are the delimiters that enclose template actions.
+	Delims tutil.Delimiters
+
+	// Escape is the sequence that stands for a literal left delimiter in
+	// text. It defaults to a backslash followed by the left delimiter.
+	Escape []byte
+
+	// HeadingIDFunc is the template function that generates the auto IDs of
+	// headings with actions at execution time. If it is empty, such
+	// headings get IDs generated from their source like other headings.
+	HeadingIDFunc string
+
+	// LinkAttributes enables attribute lists right after links and images,
+	// such as [Buy]({{ .URL }}){.btn}.
+	LinkAttributes bool
+}
+
+// An ActionOption is a functional option for the action-aware parsers.
+type ActionOption func(*ActionConfig)
+
+// NewActionConfig returns an ActionConfig with the given options applied.
+func NewActionConfig(opts ...ActionOption) ActionConfig {
+	c := ActionConfig{
+		Delims: tutil.DefaultDelimiters,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+	if len(c.Escape) == 0 {
+		c.Escape = append([]byte{'\\'}, c.Delims.Left...)
+	}
+	return c
+}
+
+// WithDelims is a functional option that sets the action delimiters, like
+// text/template's Template.Delims. An empty delimiter stands for the default.
+func WithDelims(left, right string) ActionOption {
+	return func(c *ActionConfig) {
+		c.Delims = tutil.NewDelimiters(left, right)
+	}
+}
+
+// WithEscape is a functional option that sets the sequence that stands for
+// a literal left delimiter in text, in place of a backslash followed by the
+// left delimiter. Like a backslash escape, it must start with an ASCII
+// punctuation character.
+func WithEscape(escape string) ActionOption {
+	return func(c *ActionConfig) {
+		c.Escape = []byte(escape)
+	}
+}
+
+// WithHeadingIDFunc is a functional option that makes the auto ID of a
+// heading with actions an action calling the template function name with
+// the static text and action values of the heading, such as
+// {{ anchorize "Intro to " .Title }}. It only applies with the
+// AutoHeadingID parser option.
+func WithHeadingIDFunc(name string) ActionOption {
+	return func(c *ActionConfig) {
+		c.HeadingIDFunc = name
+	}
+}
+
+// WithLinkAttributes is a functional option that enables attribute lists
+// right after links and images, such as ![logo]({{ .Logo }}){width="{{ .W }}"}
+// or [Buy]({{ .URL }}){.btn}.
+func WithLinkAttributes() ActionOption {
+	return func(c *ActionConfig) {
+		c.LinkAttributes = true
+	}
+}
+
+// withActionConfig passes an ActionConfig to the heading parsers.
+type withActionConfig struct {
+	Option
+	config ActionConfig
+}
+
+func (o *withActionConfig) SetHeadingOption(p *HeadingConfig) {
+	p.ActionConfig = o.config
+}
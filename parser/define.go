@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"github.com/hermit-ink/goldmark-template/ast"
+	gast "github.com/yuin/goldmark/ast"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// defineHoister is an ASTTransformer that moves block-level {{ define }}
+// blocks to the end of the document.
+type defineHoister struct {
+}
+
+// NewDefineHoister returns a new ASTTransformer that moves every
+// block-level {{ define }} that is at the top level of the template out of
+// the Markdown blocks around it and to the end of the document, which keeps
+// the whitespace between them out of the main template. A {{ define }}
+// inside another block action, such as {{ if }}, is left in place, since
+// text/template rejects it wherever it ends up.
+func NewDefineHoister() gparser.ASTTransformer {
+	return &defineHoister{}
+}
+
+func (t *defineHoister) Transform(node *gast.Document, reader text.Reader, pc Context) {
+	var defines []gast.Node
+	_ = gast.Walk(node, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if !entering {
+			return gast.WalkContinue, nil
+		}
+		if block, ok := n.(*ast.TemplateBlock); ok {
+			if block.Opener.Action.Kind == ast.ActionDefine {
+				defines = append(defines, n)
+			}
+			return gast.WalkSkipChildren, nil
+		}
+		return gast.WalkContinue, nil
+	})
+	for _, n := range defines {
+		n.Parent().RemoveChild(n.Parent(), n)
+		node.AppendChild(node, n)
+	}
+}
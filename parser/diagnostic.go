@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"github.com/yuin/goldmark/text"
+)
+
+// A Diagnostic reports a problem found in a document that does not stop it
+// from being converted.
+type Diagnostic struct {
+	// Segment is the part of the source the diagnostic is about.
+	Segment text.Segment
+
+	// Message describes the problem.
+	Message string
+}
+
+var diagnosticsKey = NewContextKey()
+
+// AddDiagnostic records a Diagnostic in the parser context.
+func AddDiagnostic(pc Context, d Diagnostic) {
+	list, _ := pc.Get(diagnosticsKey).([]Diagnostic)
+	pc.Set(diagnosticsKey, append(list, d))
+}
+
+// Diagnostics returns the diagnostics recorded while parsing with pc. Pass
+// your own context to Convert with parser.WithContext to read them.
+func Diagnostics(pc Context) []Diagnostic {
+	list, _ := pc.Get(diagnosticsKey).([]Diagnostic)
+	return list
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// fencedCodeBlockParser is goldmark's fenced code block parser with
+// attribute lists at the end of the info string, such as
+// ```go {data-file="{{ .Path }}" .numbered}.
+type fencedCodeBlockParser struct {
+	BlockParser
+	ActionConfig
+	attribute bool
+}
+
+// NewFencedCodeBlockParser returns a new BlockParser that parses fenced code
+// blocks. With the Attribute parser option, an attribute list at the end of
+// the info string is set on the block, actions included, and removed from
+// the info string.
+func NewFencedCodeBlockParser(opts ...ActionOption) BlockParser {
+	return &fencedCodeBlockParser{
+		BlockParser:  gparser.NewFencedCodeBlockParser(),
+		ActionConfig: NewActionConfig(opts...),
+	}
+}
+
+// SetOption implements SetOptioner.
+func (b *fencedCodeBlockParser) SetOption(name OptionName, _ interface{}) {
+	if name == optAttribute {
+		b.attribute = true
+	}
+}
+
+func (b *fencedCodeBlockParser) Open(parent gast.Node, reader text.Reader, pc Context) (gast.Node, State) {
+	node, state := b.BlockParser.Open(parent, reader, pc)
+	if n, ok := node.(*gast.FencedCodeBlock); ok && b.attribute && n.Info != nil {
+		b.parseInfoAttributes(n, reader.Source(), pc)
+	}
+	return node, state
+}
+
+// parseInfoAttributes moves the attribute list at the end of the info
+// string of n to its attributes.
+func (b *fencedCodeBlockParser) parseInfoAttributes(n *gast.FencedCodeBlock, source []byte, pc Context) {
+	segment := n.Info.Segment
+	info := segment.Value(source)
+	start := -1
+	for i := 0; i < len(info); i++ {
+		if end := b.Delims.FindActionEnd(info, i); end > 0 {
+			i = end - 1
+			continue
+		}
+		if info[i] == '{' {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return
+	}
+	reader := text.NewReader(info[start:])
+	attrs, ok := parseAttributes(reader, b.Delims)
+	rest, _ := reader.PeekLine()
+	if !ok || !util.IsBlank(rest) {
+		return
+	}
+	setAttributes(valueSegments(pc), n, attrs, segment.Start+start)
+	stop := segment.Start + start - util.TrimRightSpaceLength(info[:start])
+	if stop == segment.Start {
+		n.Info = nil
+		return
+	}
+	n.Info = gast.NewTextSegment(text.NewSegment(segment.Start, stop))
+}
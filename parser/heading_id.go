@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// generateHeadingID sets the id of node, a heading, the way the AutoHeadingID
+// option does. If c names a heading ID function, the id is an action that
+// calls the function instead, so that every id is generated, and kept
+// unique, when the template runs. A heading with actions passes the
+// function its parts, such as {{ anchorize "Intro to " .Title }}, so that
+// its id is generated from the text the template prints; any other heading
+// passes its source, as in {{ anchorize "Static Title" }}.
+func generateHeadingID(node *gast.Heading, reader text.Reader, pc Context, c ActionConfig) {
+	if c.HeadingIDFunc != "" {
+		id := actionHeadingID(node, reader.Source(), c)
+		if id == nil {
+			id = staticHeadingID(node, reader.Source(), c)
+		}
+		node.SetAttribute(attrNameID, id)
+		return
+	}
+	generateAutoHeadingID(node, reader, pc)
+}
+
+// staticHeadingID returns the action that generates the id of node from its
+// last line as it is in the source.
+func staticHeadingID(node *gast.Heading, source []byte, c ActionConfig) []byte {
+	var line []byte
+	if lastIndex := node.Lines().Len() - 1; lastIndex >= 0 {
+		lastLine := node.Lines().At(lastIndex)
+		line = lastLine.Value(source)
+	}
+	return headingIDAction(c, []string{strconv.Quote(string(line))})
+}
+
+// actionHeadingID returns the action that generates the id of node, or nil
+// if its last line, which goldmark generates ids from, has no actions or has
+// actions that do not print a single value, such as {{ if .A }}.
+func actionHeadingID(node *gast.Heading, source []byte, c ActionConfig) []byte {
+	lastIndex := node.Lines().Len() - 1
+	if lastIndex < 0 {
+		return nil
+	}
+	lastLine := node.Lines().At(lastIndex)
+	line := lastLine.Value(source)
+	if !c.Delims.ContainsAction(line) {
+		return nil
+	}
+
+	var args []string
+	var static []byte
+	trimLeft := false
+	flush := func(trimRight bool) {
+		if trimLeft {
+			static = bytes.TrimLeft(static, " \t\r\n")
+		}
+		if trimRight {
+			static = bytes.TrimRight(static, " \t\r\n")
+		}
+		if len(static) > 0 {
+			args = append(args, strconv.Quote(string(static)))
+		}
+		static = static[:0]
+	}
+	for i := 0; i < len(line); {
+		if bytes.HasPrefix(line[i:], c.Escape) {
+			static = append(static, line[i:i+len(c.Escape)]...)
+			i += len(c.Escape)
+			continue
+		}
+		end := -1
+		if bytes.HasPrefix(line[i:], c.Delims.Left) {
+			end = c.Delims.FindActionEnd(line, i)
+		}
+		if end < 0 {
+			static = append(static, line[i])
+			i++
+			continue
+		}
+		action, err := ast.ParseActionDelims(line[i:end], c.Delims)
+		if err != nil {
+			return nil
+		}
+		switch {
+		case action.Kind == ast.ActionComment:
+		case action.Kind == ast.ActionPipeline && len(action.Pipe.Decl) == 0:
+		default:
+			return nil
+		}
+		flush(action.TrimLeft)
+		if action.Kind == ast.ActionPipeline {
+			args = append(args, pipeArg(action))
+		}
+		trimLeft = action.TrimRight
+		i = end
+	}
+	flush(false)
+	return headingIDAction(c, args)
+}
+
+// headingIDAction returns an action that calls the heading ID function of c
+// with args.
+func headingIDAction(c ActionConfig, args []string) []byte {
+	var id bytes.Buffer
+	id.Write(c.Delims.Left)
+	id.WriteByte(' ')
+	id.WriteString(c.HeadingIDFunc)
+	for _, arg := range args {
+		id.WriteByte(' ')
+		id.WriteString(arg)
+	}
+	id.WriteByte(' ')
+	id.Write(c.Delims.Right)
+	return id.Bytes()
+}
+
+// pipeArg returns the pipeline of action as an argument to a function,
+// parenthesized unless it is a single operand.
+func pipeArg(action *ast.Action) string {
+	pipe := action.Pipe.String()
+	if len(action.Pipe.Cmds) == 1 && len(action.Pipe.Cmds[0].Args) == 1 {
+		return pipe
+	}
+	return "(" + strings.TrimSpace(pipe) + ")"
+}
@@ -1,9 +1,11 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
+	tast "github.com/hermit-ink/goldmark-template/ast"
 	tutil "github.com/hermit-ink/goldmark-template/util"
 	"github.com/yuin/goldmark/ast"
 	gparser "github.com/yuin/goldmark/parser"
@@ -117,13 +119,15 @@ func removeLinkLabelState(pc Context, d *linkLabelState) {
 	d.Last = nil
 }
 
-type linkParser struct{}
-
-var defaultLinkParser = &linkParser{}
+type linkParser struct {
+	ActionConfig
+}
 
 // NewLinkParser returns a new InlineParser that parses links with  go template support.
-func NewLinkParser() InlineParser {
-	return defaultLinkParser
+func NewLinkParser(opts ...ActionOption) InlineParser {
+	return &linkParser{
+		ActionConfig: NewActionConfig(opts...),
+	}
 }
 
 func (s *linkParser) Trigger() []byte {
@@ -176,11 +180,12 @@ func (s *linkParser) Parse(parent ast.Node, block text.Reader, pc Context) ast.N
 	c := block.Peek()
 	l, pos := block.Position()
 	var link *ast.Link
+	var segments linkSegments
 	var hasValue bool
 	if c == '(' { // normal link
-		link = s.parseLink(parent, last, block, pc)
+		link, segments = s.parseLink(parent, last, block, pc)
 	} else if c == '[' { // reference link
-		link, hasValue = s.parseReferenceLink(parent, last, block, pc)
+		link, segments, hasValue = s.parseReferenceLink(parent, last, block, pc)
 		if link == nil && hasValue {
 			ast.MergeOrReplaceTextSegment(last.Parent(), last, last.Segment)
 			_ = popLinkBottom(pc)
@@ -211,13 +216,33 @@ func (s *linkParser) Parse(parent ast.Node, block text.Reader, pc Context) ast.N
 		s.processLinkLabel(parent, link, last, pc)
 		link.Title = ref.Title()
 		link.Destination = ref.Destination()
+		segments = referenceSegments(pc, maybeReference)
 	}
+	var node ast.Node = link
 	if last.IsImage {
-		last.Parent().RemoveChild(last.Parent(), last)
-		return ast.NewImage(link)
+		node = ast.NewImage(link)
+	}
+	values := valueSegments(pc)
+	values.Set(node, tast.FieldDestination, segments.destination)
+	values.Set(node, tast.FieldTitle, segments.title)
+	if s.LinkAttributes {
+		if attrs, ok := s.parseLinkAttributes(block); ok {
+			setAttributes(values, node, attrs, 0)
+		}
 	}
 	last.Parent().RemoveChild(last.Parent(), last)
-	return link
+	return node
+}
+
+// parseLinkAttributes parses the attribute list right after a link or an
+// image, such as {.btn data-sku="{{ .SKU }}"}. An action, such as the
+// {{ .Price }} in [Buy](/buy){{ .Price }}, is not an attribute list.
+func (s *linkParser) parseLinkAttributes(block text.Reader) (Attributes, bool) {
+	line, _ := block.PeekLine()
+	if len(line) == 0 || line[0] != '{' || bytes.HasPrefix(line, s.Delims.Left) {
+		return nil, false
+	}
+	return parseAttributes(block, s.Delims)
 }
 
 func (s *linkParser) containsLink(n ast.Node) bool {
@@ -257,20 +282,84 @@ func (s *linkParser) processLinkLabel(parent ast.Node, link *ast.Link, last *lin
 	}
 }
 
-var linkFindClosureOptions text.FindClosureOptions = text.FindClosureOptions{
-	Nesting: false,
-	Newline: true,
-	Advance: true,
+// findLinkClosure is block.FindClosure without nesting, across lines and
+// advancing the reader, as used for link labels and titles, except that
+// openers and closers inside actions, like the "]" in {{ index .Labels "]" }},
+// are skipped.
+func findLinkClosure(block text.Reader, opener, closer byte, delims tutil.Delimiters) (*text.Segments, bool) {
+	var segments *text.Segments
+	for {
+		line, segment := block.PeekLine()
+		if line == nil {
+			return nil, false
+		}
+		for i := 0; i < len(line); i++ {
+			if end := delims.FindActionEnd(line, i); end > 0 {
+				i = end - 1
+				continue
+			}
+			c := line[i]
+			if c == '\\' && i < len(line)-1 && util.IsPunct(line[i+1]) {
+				i++
+			} else if c == closer {
+				if segments == nil {
+					segments = text.NewSegments()
+				}
+				segments.Append(segment.WithStop(segment.Start + i))
+				block.Advance(i + 1)
+				return segments, true
+			} else if c == opener {
+				return nil, false
+			}
+		}
+		block.AdvanceLine()
+		if segments == nil {
+			segments = text.NewSegments()
+		}
+		segments.Append(segment)
+	}
+}
+
+// linkSegments are the segments that the destination and title of a link
+// were copied from, if they are verbatim copies of the source.
+type linkSegments struct {
+	destination text.Segment
+	title       text.Segment
+}
+
+var referenceSegmentsKey = NewContextKey()
+
+// addReference adds a link reference definition to pc like
+// pc.AddReference, and records the segments of its destination and title
+// for the links that use it.
+func addReference(pc Context, label, destination, title []byte, segments linkSegments) {
+	key := util.ToLinkReference(label)
+	if _, ok := pc.Reference(key); !ok {
+		all, _ := pc.Get(referenceSegmentsKey).(map[string]linkSegments)
+		if all == nil {
+			all = map[string]linkSegments{}
+			pc.Set(referenceSegmentsKey, all)
+		}
+		all[key] = segments
+	}
+	pc.AddReference(NewReference(label, destination, title))
+}
+
+// referenceSegments returns the segments recorded by addReference for the
+// reference label.
+func referenceSegments(pc Context, label []byte) linkSegments {
+	all, _ := pc.Get(referenceSegmentsKey).(map[string]linkSegments)
+	return all[util.ToLinkReference(label)]
 }
 
 func (s *linkParser) parseReferenceLink(parent ast.Node, last *linkLabelState,
 	block text.Reader, pc Context,
-) (*ast.Link, bool) {
+) (*ast.Link, linkSegments, bool) {
 	_, orgpos := block.Position()
 	block.Advance(1) // skip '['
-	segments, found := block.FindClosure('[', ']', linkFindClosureOptions)
+	segments, found := findLinkClosure(block, '[', ']', s.Delims)
 	if !found {
-		return nil, false
+		return nil, linkSegments{}, false
 	}
 
 	var maybeReference []byte
@@ -290,47 +379,48 @@ func (s *linkParser) parseReferenceLink(parent ast.Node, last *linkLabelState,
 	// CommonMark spec says:
 	//  > A link label can have at most 999 characters inside the square brackets.
 	if len(maybeReference) > 999 {
-		return nil, true
+		return nil, linkSegments{}, true
 	}
 
 	ref, ok := pc.Reference(util.ToLinkReference(maybeReference))
 	if !ok {
-		return nil, true
+		return nil, linkSegments{}, true
 	}
 
 	link := ast.NewLink()
 	s.processLinkLabel(parent, link, last, pc)
 	link.Title = ref.Title()
 	link.Destination = ref.Destination()
-	return link, true
+	return link, referenceSegments(pc, maybeReference), true
 }
 
-func (s *linkParser) parseLink(parent ast.Node, last *linkLabelState, block text.Reader, pc Context) *ast.Link {
+func (s *linkParser) parseLink(parent ast.Node, last *linkLabelState, block text.Reader, pc Context) (*ast.Link, linkSegments) {
 	block.Advance(1) // skip '('
 	block.SkipSpaces()
 	var title []byte
 	var destination []byte
+	var segments linkSegments
 	var ok bool
 	if block.Peek() == ')' { // empty link like '[link]()'
 		block.Advance(1)
 	} else {
-		destination, ok = parseLinkDestination(block)
+		destination, segments.destination, ok = parseLinkDestination(block, s.Delims)
 		if !ok {
-			return nil
+			return nil, segments
 		}
 		block.SkipSpaces()
 		if block.Peek() == ')' {
 			block.Advance(1)
 		} else {
-			title, ok = parseLinkTitle(block)
+			title, segments.title, ok = parseLinkTitle(block, s.Delims)
 			if !ok {
-				return nil
+				return nil, segments
 			}
 			block.SkipSpaces()
 			if block.Peek() == ')' {
 				block.Advance(1)
 			} else {
-				return nil
+				return nil, segments
 			}
 		}
 	}
@@ -339,13 +429,14 @@ func (s *linkParser) parseLink(parent ast.Node, last *linkLabelState, block text
 	s.processLinkLabel(parent, link, last, pc)
 	link.Destination = destination
 	link.Title = title
-	return link
+	return link, segments
 }
 
-// parseLinkDestination is our template-aware version
-func parseLinkDestination(block text.Reader) ([]byte, bool) {
+// parseLinkDestination is our template-aware version. It also returns the
+// segment the destination was copied from.
+func parseLinkDestination(block text.Reader, delims tutil.Delimiters) ([]byte, text.Segment, bool) {
 	block.SkipSpaces()
-	line, _ := block.PeekLine()
+	line, segment := block.PeekLine()
 	if block.Peek() == '<' {
 		i := 1
 		for i < len(line) {
@@ -355,15 +446,15 @@ func parseLinkDestination(block text.Reader) ([]byte, bool) {
 				continue
 			} else if c == '>' {
 				block.Advance(i + 1)
-				return line[1:i], true
+				return line[1:i], lineSegment(segment, 1, i), true
 			}
 			i++
 		}
-		return nil, false
+		return nil, text.Segment{}, false
 	}
 	opened := 0
 	i := 0
-	actionTracker := tutil.NewActionState()
+	actionTracker := delims.NewActionState()
 
 	for i < len(line) {
 		c := line[i]
@@ -391,38 +482,49 @@ func parseLinkDestination(block text.Reader) ([]byte, bool) {
 	dest := line[:i]
 
 	// Templates are always valid, otherwise use original validation
-	if tutil.ContainsAction(dest) {
-		return dest, true
+	if delims.ContainsAction(dest) {
+		return dest, lineSegment(segment, 0, i), true
 	}
 
 	// For non-template URLs, use original goldmark logic
-	return dest, len(dest) != 0
+	return dest, lineSegment(segment, 0, i), len(dest) != 0
 }
 
-func parseLinkTitle(block text.Reader) ([]byte, bool) {
+// parseLinkTitle parses a link title. It also returns the segment the title
+// was copied from, if it is on a single line.
+func parseLinkTitle(block text.Reader, delims tutil.Delimiters) ([]byte, text.Segment, bool) {
 	block.SkipSpaces()
 	opener := block.Peek()
 	if opener != '"' && opener != '\'' && opener != '(' {
-		return nil, false
+		return nil, text.Segment{}, false
 	}
 	closer := opener
 	if opener == '(' {
 		closer = ')'
 	}
 	block.Advance(1)
-	segments, found := block.FindClosure(opener, closer, linkFindClosureOptions)
+	segments, found := findLinkClosure(block, opener, closer, delims)
 	if found {
 		if segments.Len() == 1 {
-			return block.Value(segments.At(0)), true
+			return block.Value(segments.At(0)), verbatimSegment(segments.At(0)), true
 		}
 		var title []byte
 		for i := 0; i < segments.Len(); i++ {
 			s := segments.At(i)
 			title = append(title, block.Value(s)...)
 		}
-		return title, true
+		return title, text.Segment{}, true
+	}
+	return nil, text.Segment{}, false
+}
+
+// verbatimSegment returns s if its value is a verbatim copy of the source,
+// and an empty segment if it has padding.
+func verbatimSegment(s text.Segment) text.Segment {
+	if s.Padding != 0 {
+		return text.Segment{}
 	}
-	return nil, false
+	return s
 }
 
 func pushLinkBottom(pc Context) {
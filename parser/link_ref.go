@@ -1,6 +1,7 @@
 package parser
 
 import (
+	tutil "github.com/hermit-ink/goldmark-template/util"
 	"github.com/yuin/goldmark/ast"
 	gparser "github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
@@ -12,18 +13,27 @@ var (
 )
 
 type linkReferenceParagraphTransformer struct {
+	ActionConfig
 }
 
 // LinkReferenceParagraphTransformer is a ParagraphTransformer implementation
 // that parses and extracts link reference from paragraphs.
-var LinkReferenceParagraphTransformer = &linkReferenceParagraphTransformer{}
+var LinkReferenceParagraphTransformer = NewLinkReferenceParagraphTransformer()
+
+// NewLinkReferenceParagraphTransformer returns a new ParagraphTransformer
+// that parses and extracts link reference from paragraphs.
+func NewLinkReferenceParagraphTransformer(opts ...ActionOption) gparser.ParagraphTransformer {
+	return &linkReferenceParagraphTransformer{
+		ActionConfig: NewActionConfig(opts...),
+	}
+}
 
 func (p *linkReferenceParagraphTransformer) Transform(node *ast.Paragraph, reader text.Reader, pc Context) {
 	lines := node.Lines()
 	block := text.NewBlockReader(reader.Source(), lines)
 	removes := [][2]int{}
 	for {
-		start, end := parseLinkReferenceDefinition(block, pc)
+		start, end := parseLinkReferenceDefinition(block, pc, p.Delims)
 		if start > -1 {
 			if start == end {
 				end++
@@ -55,7 +65,7 @@ func (p *linkReferenceParagraphTransformer) Transform(node *ast.Paragraph, reade
 	node.SetLines(lines)
 }
 
-func parseLinkReferenceDefinition(block text.Reader, pc Context) (int, int) {
+func parseLinkReferenceDefinition(block text.Reader, pc Context, delims tutil.Delimiters) (int, int) {
 	block.SkipSpaces()
 	line, _ := block.PeekLine()
 	if line == nil {
@@ -73,7 +83,7 @@ func parseLinkReferenceDefinition(block text.Reader, pc Context) (int, int) {
 		return -1, -1
 	}
 	block.Advance(pos + 1)
-	segments, found := block.FindClosure('[', ']', linkFindClosureOptions)
+	segments, found := findLinkClosure(block, '[', ']', delims)
 	if !found {
 		return -1, -1
 	}
@@ -95,10 +105,11 @@ func parseLinkReferenceDefinition(block text.Reader, pc Context) (int, int) {
 	block.Advance(1)
 	block.SkipSpaces()
 
-	destination, ok := parseLinkDestination(block)
+	destination, destinationSegment, ok := parseLinkDestination(block, delims)
 	if !ok {
 		return -1, -1
 	}
+	refSegments := linkSegments{destination: destinationSegment}
 	line, _ = block.PeekLine()
 	isNewLine := line == nil || util.IsBlank(line)
 
@@ -109,8 +120,7 @@ func parseLinkReferenceDefinition(block text.Reader, pc Context) (int, int) {
 		if !isNewLine {
 			return -1, -1
 		}
-		ref := NewReference(label, destination, nil)
-		pc.AddReference(ref)
+		addReference(pc, label, destination, nil, refSegments)
 		return startLine, endLine + 1
 	}
 	if spaces == 0 {
@@ -121,19 +131,19 @@ func parseLinkReferenceDefinition(block text.Reader, pc Context) (int, int) {
 	if opener == '(' {
 		closer = ')'
 	}
-	segments, found = block.FindClosure(opener, closer, linkFindClosureOptions)
+	segments, found = findLinkClosure(block, opener, closer, delims)
 	if !found {
 		if !isNewLine {
 			return -1, -1
 		}
-		ref := NewReference(label, destination, nil)
-		pc.AddReference(ref)
+		addReference(pc, label, destination, nil, refSegments)
 		block.AdvanceLine()
 		return startLine, endLine + 1
 	}
 	var title []byte
 	if segments.Len() == 1 {
 		title = block.Value(segments.At(0))
+		refSegments.title = verbatimSegment(segments.At(0))
 	} else {
 		for i := 0; i < segments.Len(); i++ {
 			s := segments.At(i)
@@ -146,13 +156,11 @@ func parseLinkReferenceDefinition(block text.Reader, pc Context) (int, int) {
 		if !isNewLine {
 			return -1, -1
 		}
-		ref := NewReference(label, destination, title)
-		pc.AddReference(ref)
+		addReference(pc, label, destination, title, refSegments)
 		return startLine, endLine
 	}
 
 	endLine, _ = block.Position()
-	ref := NewReference(label, destination, title)
-	pc.AddReference(ref)
+	addReference(pc, label, destination, title, refSegments)
 	return startLine, endLine + 1
 }
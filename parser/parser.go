@@ -5,36 +5,54 @@ import (
 	"github.com/yuin/goldmark/util"
 )
 
-func ActionAwareParsers() gparser.Parser {
+// ActionAwareParsers returns a parser whose inline and block parsers
+// preserve template actions. The options apply to every action-aware parser.
+func ActionAwareParsers(opts ...ActionOption) gparser.Parser {
+	config := NewActionConfig(opts...)
+	withConfig := func(c *ActionConfig) { *c = config }
+
 	inlineParsers := []util.PrioritizedValue{
-		util.Prioritized(NewCodeSpanParser(), 100),
-		util.Prioritized(NewLinkParser(), 200),
-		util.Prioritized(NewAutoLinkParser(), 300),
+		util.Prioritized(NewCodeSpanParser(withConfig), 100),
+		util.Prioritized(NewTemplateEscapeParser(withConfig), 140),
+		// Template actions come before links so that delimiters such as
+		// [[ and ]] are not mistaken for link labels.
+		util.Prioritized(NewTemplateActionParser(withConfig), 150),
+		util.Prioritized(NewLinkParser(withConfig), 200),
+		util.Prioritized(NewAutoLinkParser(withConfig), 300),
 		util.Prioritized(gparser.NewRawHTMLParser(), 400),
 		util.Prioritized(gparser.NewEmphasisParser(), 500),
-		util.Prioritized(NewTemplateActionParser(), 600),
 	}
 
 	blockParsers := []util.PrioritizedValue{
-		util.Prioritized(gparser.NewSetextHeadingParser(), 100),
+		util.Prioritized(NewSetextHeadingParser(&withActionConfig{config: config}), 100),
 		util.Prioritized(gparser.NewThematicBreakParser(), 200),
 		util.Prioritized(gparser.NewListParser(), 300),
 		util.Prioritized(gparser.NewListItemParser(), 400),
 		util.Prioritized(gparser.NewCodeBlockParser(), 500),
-		util.Prioritized(NewATXHeadingParser(), 600),
-		util.Prioritized(gparser.NewFencedCodeBlockParser(), 700),
+		util.Prioritized(NewATXHeadingParser(&withActionConfig{config: config}), 600),
+		util.Prioritized(NewFencedCodeBlockParser(withConfig), 700),
 		util.Prioritized(gparser.NewBlockquoteParser(), 800),
 		util.Prioritized(gparser.NewHTMLBlockParser(), 900),
+		util.Prioritized(NewTemplateBlockParser(withConfig), 950),
+		util.Prioritized(NewBlockAttributeParser(withConfig), 960),
 		util.Prioritized(gparser.NewParagraphParser(), 1000),
 	}
 
 	paragraphTransformers := []util.PrioritizedValue{
-		util.Prioritized(LinkReferenceParagraphTransformer, 100),
+		util.Prioritized(NewLinkReferenceParagraphTransformer(withConfig), 100),
+	}
+
+	astTransformers := []util.PrioritizedValue{
+		util.Prioritized(NewBlockAttributeAttacher(), 50),
+		util.Prioritized(NewTrimMarkerChecker(withConfig), 100),
+		util.Prioritized(NewDefineHoister(), 200),
+		util.Prioritized(NewValueSegmentsAppender(), 1000),
 	}
 
 	return gparser.NewParser(
 		gparser.WithBlockParsers(blockParsers...),
 		gparser.WithInlineParsers(inlineParsers...),
 		gparser.WithParagraphTransformers(paragraphTransformers...),
+		gparser.WithASTTransformers(astTransformers...),
 	)
 }
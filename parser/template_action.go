@@ -1,42 +1,48 @@
 package parser
 
 import (
+	"bytes"
+
 	"github.com/hermit-ink/goldmark-template/ast"
-	tutil "github.com/hermit-ink/goldmark-template/util"
 	gast "github.com/yuin/goldmark/ast"
 	gparser "github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
 )
 
-// templateActionParser is an inline parser for Go template actions
-type templateActionParser struct{}
+// templateActionParser is an inline parser for Go template actions. Every
+// action it finds is classified and parsed by ast.NewTemplateAction.
+type templateActionParser struct {
+	ActionConfig
+}
 
 // NewTemplateActionParser returns a new InlineParser that parses go template
 // actions
-func NewTemplateActionParser() gparser.InlineParser {
-	return &templateActionParser{}
+func NewTemplateActionParser(opts ...ActionOption) gparser.InlineParser {
+	return &templateActionParser{
+		ActionConfig: NewActionConfig(opts...),
+	}
 }
 
 // Trigger returns characters that trigger this parser
 func (s *templateActionParser) Trigger() []byte {
-	return []byte{'{'}
+	return []byte{s.Delims.Left[0]}
 }
 
 func (s *templateActionParser) Parse(parent gast.Node, block text.Reader, pc gparser.Context) gast.Node {
 	line, segment := block.PeekLine()
 
-	if len(line) < 2 || line[0] != '{' || line[1] != '{' {
+	if !bytes.HasPrefix(line, s.Delims.Left) {
 		return nil
 	}
 
-	endPos := tutil.FindActionEnd(line, 0)
+	endPos := s.Delims.FindActionEnd(line, 0)
 	if endPos == -1 {
 		return nil
 	}
 
 	content := line[0:endPos]
 	nodeSegment := segment.WithStop(segment.Start + endPos)
-	node := ast.NewTemplateAction(content, nodeSegment)
+	node := ast.NewTemplateActionDelims(content, nodeSegment, s.Delims)
 	block.Advance(endPos)
 	return node
 }
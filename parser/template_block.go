@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// templateBlockParser is a block parser for control-flow actions that stand
+// alone on a line. Openers (if, range, with, define, block) become
+// TemplateBlock containers that hold the Markdown blocks up to the matching
+// {{ end }}; else branches, unmatched ends and template calls become
+// TemplateActionBlocks.
+//
+// An {{ end }} only closes a block opened in the same container, so that the
+// HTML of each branch stays well nested. An end outside the container of its
+// opener, as in a block quote that ends before it, closes nothing and is
+// reported as a Diagnostic.
+type templateBlockParser struct {
+	ActionConfig
+}
+
+// NewTemplateBlockParser returns a new BlockParser that parses action-only
+// lines for if/range/with/else/end/define/block/template.
+func NewTemplateBlockParser(opts ...ActionOption) BlockParser {
+	return &templateBlockParser{
+		ActionConfig: NewActionConfig(opts...),
+	}
+}
+
+func (b *templateBlockParser) Trigger() []byte {
+	return []byte{b.Delims.Left[0]}
+}
+
+func (b *templateBlockParser) Open(parent gast.Node, reader text.Reader, pc Context) (gast.Node, State) {
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, NoChildren
+	}
+	action, advance := actionLine(reader, pos, b.Delims)
+	if action == nil {
+		return nil, NoChildren
+	}
+	kind := action.Action.Kind
+	switch {
+	case kind.OpensBlock():
+		reader.Advance(advance)
+		return ast.NewTemplateBlock(action), gparser.HasChildren
+	case kind.IsBranch() || kind == ast.ActionEnd:
+		if kind == ast.ActionEnd {
+			checkCutOff(pc, action)
+		}
+		reader.Advance(advance)
+		return ast.NewTemplateActionBlock(action), NoChildren
+	case kind == ast.ActionTemplate:
+		// A template call stands in for blocks of its own, but only
+		// when it does not continue a paragraph.
+		if last := pc.LastOpenedBlock().Node; last != nil && last.Kind() == gast.KindParagraph {
+			return nil, NoChildren
+		}
+		reader.Advance(advance)
+		return ast.NewTemplateActionBlock(action), NoChildren
+	}
+	return nil, NoChildren
+}
+
+func (b *templateBlockParser) Continue(node gast.Node, reader text.Reader, pc Context) State {
+	block, ok := node.(*ast.TemplateBlock)
+	line, _ := reader.PeekLine()
+	if !ok || line == nil {
+		return Close
+	}
+	// An {{ end }} belongs to the innermost block that can hold one, and
+	// never to the content of a code or HTML block.
+	opened := pc.OpenedBlocks()
+	for i := len(opened) - 1; i >= 0 && opened[i].Node != node; i-- {
+		if _, ok := opened[i].Node.(*ast.TemplateBlock); ok || opened[i].Node.IsRaw() {
+			return gparser.Continue | gparser.HasChildren
+		}
+	}
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if w > 3 {
+		return gparser.Continue | gparser.HasChildren
+	}
+	action, advance := actionLine(reader, pos, b.Delims)
+	if action != nil && action.Action.Kind == ast.ActionEnd {
+		block.Closer = action
+		reader.Advance(advance)
+		return Close
+	}
+	return gparser.Continue | gparser.HasChildren
+}
+
+func (b *templateBlockParser) Close(node gast.Node, reader text.Reader, pc Context) {
+	// A block that closes before the document ends without its {{ end }}
+	// was cut off by the end of its container.
+	if block, ok := node.(*ast.TemplateBlock); ok && block.Closer == nil {
+		if line, _ := reader.PeekLine(); line != nil {
+			cutOffBlocksOf(pc).add(block)
+		}
+	}
+}
+
+func (b *templateBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *templateBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// cutOffBlocks holds the TemplateBlocks whose container ended before their
+// {{ end }}, innermost last.
+type cutOffBlocks struct {
+	blocks []*ast.TemplateBlock
+	ended  map[*ast.TemplateBlock]bool
+}
+
+var cutOffKey = NewContextKey()
+
+func cutOffBlocksOf(pc Context) *cutOffBlocks {
+	c, _ := pc.Get(cutOffKey).(*cutOffBlocks)
+	if c == nil {
+		c = &cutOffBlocks{ended: map[*ast.TemplateBlock]bool{}}
+		pc.Set(cutOffKey, c)
+	}
+	return c
+}
+
+func (c *cutOffBlocks) add(block *ast.TemplateBlock) {
+	if !c.ended[block] {
+		c.blocks = append(c.blocks, block)
+	}
+}
+
+// checkCutOff reports end, an {{ end }} that no open block matched, when a
+// block was cut off by the end of its container before it, as the end the
+// block is missing. The container may end on the line of end itself, in
+// which case the block is still open.
+func checkCutOff(pc Context, end *ast.TemplateAction) {
+	c := cutOffBlocksOf(pc)
+	var block *ast.TemplateBlock
+	opened := pc.OpenedBlocks()
+	for i := len(opened) - 1; i >= 0 && block == nil; i-- {
+		block, _ = opened[i].Node.(*ast.TemplateBlock)
+	}
+	if block != nil {
+		c.ended[block] = true
+	} else if len(c.blocks) > 0 {
+		block = c.blocks[len(c.blocks)-1]
+		c.blocks = c.blocks[:len(c.blocks)-1]
+	} else {
+		return
+	}
+	AddDiagnostic(pc, Diagnostic{
+		Segment: end.Segment,
+		Message: "end is outside the container of the " + block.Opener.Action.Kind.Keyword() + " it closes",
+	})
+}
+
+// actionLine returns the action starting at pos if it is the only thing on
+// the current line, along with the number of bytes to advance past it.
+func actionLine(reader text.Reader, pos int, delims tutil.Delimiters) (*ast.TemplateAction, int) {
+	line, segment := reader.PeekLine()
+	if pos >= len(line) {
+		return nil, 0
+	}
+	end := delims.FindActionEnd(line, pos)
+	if end < 0 || !util.IsBlank(line[end:]) {
+		return nil, 0
+	}
+	start := segment.Start + pos - segment.Padding
+	action := ast.NewTemplateActionDelims(line[pos:end], text.NewSegment(start, start+end-pos), delims)
+	advance := len(line)
+	if line[advance-1] == '\n' {
+		advance--
+	}
+	return action, advance
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"bytes"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	gast "github.com/yuin/goldmark/ast"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// templateEscapeParser is an inline parser for escaped left delimiters, such
+// as \{{, which stand for the delimiter as text.
+type templateEscapeParser struct {
+	ActionConfig
+}
+
+// NewTemplateEscapeParser returns a new InlineParser that parses the escape
+// sequence for a literal left delimiter, \{{ by default.
+func NewTemplateEscapeParser(opts ...ActionOption) gparser.InlineParser {
+	return &templateEscapeParser{
+		ActionConfig: NewActionConfig(opts...),
+	}
+}
+
+// Trigger returns characters that trigger this parser
+func (s *templateEscapeParser) Trigger() []byte {
+	return []byte{s.Escape[0]}
+}
+
+func (s *templateEscapeParser) Parse(parent gast.Node, block text.Reader, pc gparser.Context) gast.Node {
+	line, segment := block.PeekLine()
+	if !bytes.HasPrefix(line, s.Escape) {
+		return nil
+	}
+	block.Advance(len(s.Escape))
+	return ast.NewTemplateEscape(segment.WithStop(segment.Start + len(s.Escape)))
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"bytes"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	gast "github.com/yuin/goldmark/ast"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// trimMarkerChecker is an ASTTransformer that reports trim markers that
+// would remove whitespace from the content of code spans and code blocks
+// when the rendered template is executed.
+type trimMarkerChecker struct {
+	ActionConfig
+}
+
+// NewTrimMarkerChecker returns a new ASTTransformer that adds a Diagnostic
+// for every {{- or -}} trim marker that reaches into code content.
+func NewTrimMarkerChecker(opts ...ActionOption) gparser.ASTTransformer {
+	return &trimMarkerChecker{
+		ActionConfig: NewActionConfig(opts...),
+	}
+}
+
+func (t *trimMarkerChecker) Transform(node *gast.Document, reader text.Reader, pc Context) {
+	source := reader.Source()
+	_ = gast.Walk(node, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if !entering {
+			return gast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case gast.KindCodeBlock, gast.KindFencedCodeBlock:
+			var content codeContent
+			for i := 0; i < n.Lines().Len(); i++ {
+				content.append(source, n.Lines().At(i), false)
+			}
+			t.check(content, pc)
+			return gast.WalkSkipChildren, nil
+		case gast.KindCodeSpan:
+			var content codeContent
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if text, ok := c.(*gast.Text); ok {
+					content.append(source, text.Segment, true)
+				}
+			}
+			t.check(content, pc)
+			return gast.WalkSkipChildren, nil
+		}
+		return gast.WalkContinue, nil
+	})
+}
+
+func (t *trimMarkerChecker) check(content codeContent, pc Context) {
+	b := content.bytes
+	for i := 0; i < len(b); i++ {
+		if !bytes.HasPrefix(b[i:], t.Delims.Left) {
+			continue
+		}
+		end := t.Delims.FindActionEnd(b, i)
+		if end < 0 {
+			continue
+		}
+		action, _ := ast.ParseActionDelims(b[i:end], t.Delims)
+		if (action.TrimLeft && i > 0 && util.IsSpace(b[i-1])) ||
+			(action.TrimRight && end < len(b) && util.IsSpace(b[end])) {
+			AddDiagnostic(pc, Diagnostic{
+				Segment: text.NewSegment(content.offsets[i], content.offsets[end-1]+1),
+				Message: "trim marker removes whitespace from the content of code",
+			})
+		}
+		i = end - 1
+	}
+}
+
+// codeContent is the rendered content of a code span or block along with the
+// source offset of each of its bytes.
+type codeContent struct {
+	bytes   []byte
+	offsets []int
+}
+
+func (c *codeContent) append(source []byte, segment text.Segment, joinLines bool) {
+	value := segment.Value(source)
+	for i, b := range value {
+		if joinLines && b == '\n' {
+			b = ' '
+		}
+		c.bytes = append(c.bytes, b)
+		// padding spaces have no source of their own
+		c.offsets = append(c.offsets, segment.Start+max(i-segment.Padding, 0))
+	}
+}
@@ -0,0 +1,26 @@
+package parser
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// valueSegmentsAppender is an ASTTransformer that adds the value segments
+// recorded while parsing a document to the document.
+type valueSegmentsAppender struct {
+}
+
+// NewValueSegmentsAppender returns a new ASTTransformer that appends the
+// ast.ValueSegments recorded in the parser context while parsing a document
+// to the end of the document, where ast.ValueSegmentsOf finds them. It runs
+// after the other transformers, which may record values too.
+func NewValueSegmentsAppender() gparser.ASTTransformer {
+	return &valueSegmentsAppender{}
+}
+
+func (t *valueSegmentsAppender) Transform(node *gast.Document, reader text.Reader, pc Context) {
+	node.AppendChild(node, valueSegments(pc))
+	// A context may be reused for the next document.
+	pc.Set(valueSegmentsKey, nil)
+}
@@ -0,0 +1,169 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	"github.com/hermit-ink/goldmark-template/validate"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestActionPolicyLiteral(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "allowed contexts pass through",
+			input:    "Hi {{ .Name }} [x]({{ .URL }})",
+			expected: `<p>Hi {{ .Name }} <a href="{{ .URL }}">x</a></p>`,
+		},
+		{
+			name:     "code span",
+			input:    "`{{ .Name }}`",
+			expected: `<p><code>{{"{{ .Name }}"}}</code></p>`,
+		},
+		{
+			name:     "code block",
+			input:    "```go\nfmt.Println(\"{{ .Msg }}\")\n```",
+			expected: "<pre><code class=\"language-go\">fmt.Println(&quot;{{\"{{ .Msg }}\"}}&quot;)\n</code></pre>",
+		},
+		{
+			name:     "raw HTML",
+			input:    "<div title=\"{{ .T }}\">\n{{ .Body }}\n</div>\n\nText <span>{{ .X }}</span>",
+			expected: "<div title=\"{{\"{{ .T }}\"}}\">\n{{\"{{ .Body }}\"}}\n</div>\n<p>Text <span>{{ .X }}</span></p>",
+		},
+		{
+			name:     "heading attribute",
+			input:    "# Title {class=\"{{ .C }}\"}",
+			expected: `<h1 class="{{"{{ .C }}"}}">Title</h1>`,
+		},
+		{
+			name:     "quotes in actions",
+			input:    "`{{ printf \"%q\" .A }}`",
+			expected: `<p><code>{{"{{ printf \"%q\" .A }}"}}</code></p>`,
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(
+			WithActionPolicy(ast.ActionPolicy{
+				ast.ContextCodeSpan:  ast.PolicyLiteral,
+				ast.ContextCodeBlock: ast.PolicyLiteral,
+				ast.ContextRawHTML:   ast.PolicyLiteral,
+				ast.ContextAttribute: ast.PolicyLiteral,
+			}),
+			ParserOptions(parser.WithAttribute()),
+		)),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+
+			got := strings.TrimSpace(buf.String())
+			if got != tt.expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestActionPolicyLiteralExecutes(t *testing.T) {
+	md := goldmark.New(
+		goldmark.WithExtensions(New(WithActionPolicy(ast.ActionPolicy{
+			ast.ContextCodeSpan: ast.PolicyLiteral,
+			ast.ContextHref:     ast.PolicyLiteral,
+		}))),
+	)
+	var buf bytes.Buffer
+	if err := md.Convert([]byte("Use `{{ .Name }}` for {{ .Name }}, see [docs]({{ .URL }})"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := template.New("page").Parse(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, map[string]string{"Name": "Ada", "URL": "/x"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<p>Use <code>{{ .Name }}</code> for Ada, see <a href=\"%7b%7b%20.URL%20%7d%7d\">docs</a></p>\n"
+	if out.String() != expected {
+		t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", expected, out.String())
+	}
+}
+
+func TestActionPolicyDeny(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "allowed contexts",
+			input:    "# {{ .Title }}\n\n[x](/a \"{{ .Tip }}\")",
+			expected: nil,
+		},
+		{
+			name:  "denied contexts",
+			input: "<div>{{ .A }}</div>\n\n```\n{{ .B }}\n```\n\n## H {id=\"{{ .ID }}\"}\n\n<{{ .Home }}>",
+			expected: []string{
+				"1:6: {{ .A }}: actions are not allowed in raw HTML",
+				"4:1: {{ .B }}: actions are not allowed in code blocks",
+				"7:11: {{ .ID }}: actions are not allowed in the id attribute",
+				"9:2: {{ .Home }}: actions are not allowed in the href attribute",
+			},
+		},
+		{
+			name:     "bare attribute actions",
+			input:    "# H {.x {{ .Attrs }}}",
+			expected: []string{"1:9: {{ .Attrs }}: actions are not allowed in attribute lists"},
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(
+			WithActionPolicy(ast.ActionPolicy{
+				ast.ContextRawHTML:   ast.PolicyDeny,
+				ast.ContextCodeBlock: ast.PolicyDeny,
+				ast.ContextAttribute: ast.PolicyDeny,
+				ast.ContextHref:      ast.PolicyDeny,
+			}),
+			ParserOptions(parser.WithAttribute()),
+		)),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := md.Convert([]byte(tt.input), &buf)
+
+			var got []string
+			if err != nil {
+				var errs validate.Errors
+				if !errors.As(err, &errs) {
+					t.Fatalf("expected validate.Errors, got %T: %v", err, err)
+				}
+				for _, e := range errs {
+					got = append(got, e.Error())
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Errors mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
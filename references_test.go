@@ -0,0 +1,145 @@
+package goldmarktemplate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:  "text, href and title",
+			input: "Hi {{ .User.Name }}\n\n[x]({{ $.Site.URL }} \"{{ .Tip }}\")",
+			expected: []string{
+				"1:7 text field .User.Name [User Name]",
+				"3:8 href field $.Site.URL [Site URL]",
+				"3:26 title field .Tip [Tip]",
+			},
+		},
+		{
+			name:  "index keys and functions",
+			input: `{{ index .Params "x" | printf "%s" }}`,
+			expected: []string{
+				"1:4 text function index nil",
+				"1:10 text field .Params [Params] keys=[x]",
+				"1:24 text function printf nil",
+			},
+		},
+		{
+			name:  "range scopes dot and variables",
+			input: "{{ range $i, $p := .Posts }}\n- {{ .Title }} {{ $p.Author.Name }} {{ $i }}\n{{ end }}\n\n{{ .Footer }}",
+			expected: []string{
+				"1:20 block field .Posts [Posts] ranged",
+				"2:6 text field .Title [Posts [] Title]",
+				"2:19 text field $p.Author.Name [Posts [] Author Name]",
+				"2:40 text variable $i nil",
+				"5:4 text field .Footer [Footer]",
+			},
+		},
+		{
+			name:  "with, else and dot",
+			input: "{{ with .User }}{{ .Name }}{{ else }}{{ .Guest }}{{ end }} {{ range .Tags }}{{ . }}{{ end }}",
+			expected: []string{
+				"1:9 text field .User [User]",
+				"1:20 text field .Name [User Name]",
+				"1:41 text field .Guest [Guest]",
+				"1:69 text field .Tags [Tags] ranged",
+				"1:80 text dot . [Tags []]",
+			},
+		},
+		{
+			name:  "define has no known data",
+			input: "{{ define \"x\" }}\n{{ .Title }}\n{{ end }}",
+			expected: []string{
+				"2:4 text field .Title nil",
+			},
+		},
+		{
+			name:  "image, autolink and attribute",
+			input: "# T {class=\"{{ .Class }}\"}\n\n![{{ .Alt }}]({{ .Src }} \"{{ .ImgTitle }}\") <{{ .Base }}/p>",
+			expected: []string{
+				"1:16 attribute field .Class [Class]",
+				"3:18 src field .Src [Src]",
+				"3:6 alt field .Alt [Alt]",
+				"3:30 title field .ImgTitle [ImgTitle]",
+				"3:49 href field .Base [Base]",
+				"3:49 text field .Base [Base]",
+			},
+		},
+		{
+			name:  "code span",
+			input: "`{{ .Code }}`",
+			expected: []string{
+				"1:5 code span field .Code [Code]",
+			},
+		},
+	}
+
+	md := goldmark.New(goldmark.WithExtensions(New(ParserOptions(parser.WithAttribute()))))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := []byte(tt.input)
+			doc := md.Parser().Parse(text.NewReader(source))
+
+			var got []string
+			for _, ref := range ast.References(doc, source, tutil.DefaultDelimiters) {
+				path := "nil"
+				if ref.Path != nil {
+					path = fmt.Sprint(ref.Path)
+				}
+				s := fmt.Sprintf("%d:%d %s %s %s %s", ref.Line, ref.Column, ref.Context, ref.Kind, ref.Name, path)
+				if ref.Keys != nil {
+					s += fmt.Sprintf(" keys=%v", ref.Keys)
+				}
+				if ref.Ranged {
+					s += " ranged"
+				}
+				got = append(got, s)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("References mismatch\nExpected:\n%s\nGot:\n%s", strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestReferencesLeaveDocumentAttributesAlone(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(New(ParserOptions(parser.WithAttribute()))))
+	pc := parser.NewContext()
+	inputs := []string{
+		"[x]({{ .URL }})",
+		"# H {class=\"{{ .C }}\"}\n\n[y]({{ .Link }})",
+	}
+	expected := []string{
+		"1:8 .URL",
+		"1:16 .C|3:8 .Link",
+	}
+	// The context is reused, so that the positions of the first document
+	// must not leak into the second.
+	for i, input := range inputs {
+		source := []byte(input)
+		doc := md.Parser().Parse(text.NewReader(source), parser.WithContext(pc))
+		if attrs := doc.Attributes(); len(attrs) != 0 {
+			t.Errorf("document has attributes: %v", attrs)
+		}
+		var got []string
+		for _, ref := range ast.References(doc, source, tutil.DefaultDelimiters) {
+			got = append(got, fmt.Sprintf("%d:%d %s", ref.Line, ref.Column, ref.Name))
+		}
+		if strings.Join(got, "|") != expected[i] {
+			t.Errorf("References mismatch\nExpected: %q\nGot:      %q", expected[i], strings.Join(got, "|"))
+		}
+	}
+}
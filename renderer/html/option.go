@@ -0,0 +1,17 @@
+package html
+
+import (
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	"github.com/yuin/goldmark/renderer"
+)
+
+// OptDelims is an option name that sets the action delimiters. Its value is
+// a util.Delimiters.
+const OptDelims renderer.OptionName = "TemplateDelims"
+
+// WithDelims is a renderer option that sets the action delimiters the
+// renderers preserve, like text/template's Template.Delims. An empty
+// delimiter stands for the default.
+func WithDelims(left, right string) renderer.Option {
+	return renderer.WithOption(OptDelims, tutil.NewDelimiters(left, right))
+}
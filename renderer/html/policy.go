@@ -0,0 +1,92 @@
+package html
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// OptPolicy is an option name that sets the action policy. Its value is an
+// ast.ActionPolicy.
+const OptPolicy renderer.OptionName = "TemplateActionPolicy"
+
+// WithPolicy is a renderer option that sets what the renderers do with the
+// actions in each context. Actions in a context with ast.PolicyLiteral are
+// rendered as string constants that html/template prints as text. The
+// renderers treat ast.PolicyDeny like ast.PolicyLiteral; failing the
+// conversion is up to a validate.Policy check.
+func WithPolicy(policy ast.ActionPolicy) renderer.Option {
+	return renderer.WithOption(OptPolicy, policy)
+}
+
+// OptLiteralLanguages is an option name that sets the fenced code block
+// languages whose actions are rendered literally. Its value is a []string.
+const OptLiteralLanguages renderer.OptionName = "TemplateLiteralLanguages"
+
+// WithLiteralLanguages is a renderer option that renders the actions in
+// fenced code blocks of the given languages, such as "gotemplate", as text
+// whatever the policy for code blocks is. A fenced code block with a
+// literal=true attribute, which needs attribute lists, is rendered the same
+// way, and one with literal=false is not.
+// See ast.IsLiteralFence.
+func WithLiteralLanguages(languages ...string) renderer.Option {
+	return renderer.WithOption(OptLiteralLanguages, languages)
+}
+
+// literalAction returns an action that prints action as text, such as
+// {{"{{ .Name }}"}}.
+func literalAction(action []byte, delims tutil.Delimiters) []byte {
+	out := make([]byte, 0, len(delims.Left)+len(action)+2+len(delims.Right))
+	out = append(out, delims.Left...)
+	out = strconv.AppendQuote(out, string(action))
+	return append(out, delims.Right...)
+}
+
+// writeLiteralAction writes action, which starts at offset start in the
+// source or at -1 if its position is unknown, so that html/template prints
+// it as text. When w is a SourceMap and the position is known, the action is
+// recorded.
+func writeLiteralAction(w util.BufWriter, action []byte, delims tutil.Delimiters, start int) error {
+	out := literalAction(action, delims)
+	if m, ok := w.(*SourceMap); ok && start >= 0 {
+		m.record(len(out), text.NewSegment(start, start+len(action)))
+	}
+	_, err := w.Write(out)
+	return err
+}
+
+// writeRawActions writes b, raw HTML that starts at offset start in the
+// source or at -1, verbatim. Its actions are recorded when w is a SourceMap,
+// or rendered literally when literal is set.
+func writeRawActions(w util.BufWriter, b []byte, delims tutil.Delimiters, literal bool, start int) error {
+	if !literal {
+		mapActions(w, b, delims, start)
+		_, err := w.Write(b)
+		return err
+	}
+	n := 0
+	for i := 0; i < len(b); i++ {
+		if !bytes.HasPrefix(b[i:], delims.Left) {
+			continue
+		}
+		end := delims.FindActionEnd(b, i)
+		if end < 0 {
+			continue
+		}
+		if _, err := w.Write(b[n:i]); err != nil {
+			return err
+		}
+		if err := writeLiteralAction(w, b[i:end], delims, offsetAt(start, i)); err != nil {
+			return err
+		}
+		n = end
+		i = end - 1
+	}
+	_, err := w.Write(b[n:])
+	return err
+}
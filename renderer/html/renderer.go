@@ -2,11 +2,15 @@ package html
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/hermit-ink/goldmark-template/ast"
 	tutil "github.com/hermit-ink/goldmark-template/util"
 	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
 	ghtml "github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/util"
 )
@@ -14,20 +18,56 @@ import (
 // Renderer is a custom renderer that uses Writer
 type Renderer struct {
 	ghtml.Config
+	delims  tutil.Delimiters
+	policy  ast.ActionPolicy
+	literal ghtml.Writer
+
+	literalLanguages []string
 }
 
 // NewRenderer creates a new Renderer
 func NewRenderer(opts ...ghtml.Option) renderer.NodeRenderer {
 	r := &Renderer{
 		Config: ghtml.NewConfig(),
+		delims: tutil.DefaultDelimiters,
 	}
 	r.Writer = NewWriter()
+	r.literal = newLiteralWriter(r.delims)
 	for _, opt := range opts {
 		opt.SetHTMLOption(&r.Config)
 	}
 	return r
 }
 
+// SetOption implements renderer.SetOptioner.
+func (r *Renderer) SetOption(name renderer.OptionName, value interface{}) {
+	switch name {
+	case OptDelims:
+		r.delims = value.(tutil.Delimiters)
+		r.Writer = NewWriterDelims(r.delims)
+		r.literal = newLiteralWriter(r.delims)
+	case OptPolicy:
+		r.policy = value.(ast.ActionPolicy)
+	case OptLiteralLanguages:
+		r.literalLanguages = value.([]string)
+	default:
+		r.Config.SetOption(name, value)
+	}
+}
+
+// hasAction checks if content contains template actions
+func (r *Renderer) hasAction(content []byte) bool {
+	return r.delims.ContainsAction(content)
+}
+
+// writer returns the Writer for content whose actions end up in context c.
+func (r *Renderer) writer(c ast.ActionContext) ghtml.Writer {
+	if r.policy.Allows(c) {
+		return r.Writer
+	}
+	return r.literal
+}
+
 // RegisterFuncs registers rendering functions for code blocks and spans
 func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(gast.KindCodeBlock, r.renderCodeBlock)
@@ -37,6 +77,12 @@ func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(gast.KindImage, r.renderImage)
 	reg.Register(gast.KindAutoLink, r.renderAutoLink)
 	reg.Register(gast.KindHeading, r.renderHeading)
+	reg.Register(gast.KindHTMLBlock, r.renderHTMLBlock)
+	reg.Register(gast.KindRawHTML, r.renderRawHTML)
+	reg.Register(gast.KindParagraph, r.renderParagraph)
+	reg.Register(gast.KindList, r.renderList)
+	reg.Register(gast.KindBlockquote, r.renderBlockquote)
+	reg.Register(east.KindTable, r.renderTable)
 }
 
 func (r *Renderer) renderHeading(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
@@ -56,12 +102,101 @@ func (r *Renderer) renderHeading(w util.BufWriter, source []byte, node gast.Node
 	return gast.WalkContinue, nil
 }
 
+// renderParagraph renders a paragraph like goldmark does, with
+// template-aware attributes.
+func (r *Renderer) renderParagraph(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		if n.Attributes() != nil {
+			_, _ = w.WriteString("<p")
+			r.renderAttributes(w, n, ghtml.ParagraphAttributeFilter)
+			_ = w.WriteByte('>')
+		} else {
+			_, _ = w.WriteString("<p>")
+		}
+	} else {
+		_, _ = w.WriteString("</p>\n")
+	}
+	return gast.WalkContinue, nil
+}
+
+// renderList renders a list like goldmark does, with template-aware
+// attributes.
+func (r *Renderer) renderList(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	n := node.(*gast.List)
+	tag := "ul"
+	if n.IsOrdered() {
+		tag = "ol"
+	}
+	if entering {
+		_ = w.WriteByte('<')
+		_, _ = w.WriteString(tag)
+		if n.IsOrdered() && n.Start != 1 {
+			_, _ = fmt.Fprintf(w, " start=\"%d\"", n.Start)
+		}
+		if n.Attributes() != nil {
+			r.renderAttributes(w, n, ghtml.ListAttributeFilter)
+		}
+		_, _ = w.WriteString(">\n")
+	} else {
+		_, _ = w.WriteString("</")
+		_, _ = w.WriteString(tag)
+		_, _ = w.WriteString(">\n")
+	}
+	return gast.WalkContinue, nil
+}
+
+// renderBlockquote renders a blockquote like goldmark does, with
+// template-aware attributes.
+func (r *Renderer) renderBlockquote(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<blockquote")
+		if n.Attributes() != nil {
+			r.renderAttributes(w, n, ghtml.BlockquoteAttributeFilter)
+		}
+		_, _ = w.WriteString(">\n")
+	} else {
+		_, _ = w.WriteString("</blockquote>\n")
+	}
+	return gast.WalkContinue, nil
+}
+
+// renderTable renders the table element of a GFM table with template-aware
+// attributes. Its rows and cells are left to the table extension.
+func (r *Renderer) renderTable(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<table")
+		if n.Attributes() != nil {
+			r.renderAttributes(w, n, extension.TableAttributeFilter)
+		}
+		_, _ = w.WriteString(">\n")
+	} else {
+		_, _ = w.WriteString("</table>\n")
+	}
+	return gast.WalkContinue, nil
+}
+
+// writePre writes the opening pre tag of a code block, with its attributes.
+func (r *Renderer) writePre(w util.BufWriter, n gast.Node) error {
+	if n.Attributes() == nil {
+		_, err := w.WriteString("<pre>")
+		return err
+	}
+	if _, err := w.WriteString("<pre"); err != nil {
+		return err
+	}
+	r.renderAttributes(w, n, ghtml.GlobalAttributeFilter)
+	return w.WriteByte('>')
+}
+
 func (r *Renderer) renderCodeBlock(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
 	if entering {
-		if _, err := w.WriteString("<pre><code>"); err != nil {
+		if err := r.writePre(w, n); err != nil {
 			return gast.WalkStop, err
 		}
-		if err := r.writeLines(w, source, n); err != nil {
+		if _, err := w.WriteString("<code>"); err != nil {
+			return gast.WalkStop, err
+		}
+		if err := r.writeLines(w, source, n, ast.ContextCodeBlock); err != nil {
 			return gast.WalkStop, err
 		}
 	} else {
@@ -75,15 +210,18 @@ func (r *Renderer) renderCodeBlock(w util.BufWriter, source []byte, n gast.Node,
 func (r *Renderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
 	n := node.(*gast.FencedCodeBlock)
 	if entering {
-		if _, err := w.WriteString("<pre><code"); err != nil {
+		if err := r.writePre(w, n); err != nil {
+			return gast.WalkStop, err
+		}
+		if _, err := w.WriteString("<code"); err != nil {
 			return gast.WalkStop, err
 		}
-		language := n.Language(source)
+		language := ast.FenceLanguage(n, source, r.delims)
 		if language != nil {
 			if _, err := w.WriteString(" class=\"language-"); err != nil {
 				return gast.WalkStop, err
 			}
-			r.Writer.Write(w, language)
+			writeAt(w, r.writer(ast.ContextAttribute), language, n.Info.Segment.Start)
 			if _, err := w.WriteString("\""); err != nil {
 				return gast.WalkStop, err
 			}
@@ -91,8 +229,13 @@ func (r *Renderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node g
 		if err := w.WriteByte('>'); err != nil {
 			return gast.WalkStop, err
 		}
-		if err := r.writeLines(w, source, n); err != nil {
-			return gast.WalkStop, err
+		writer := r.writer(ast.ContextCodeBlock)
+		if ast.IsLiteralFence(n, source, r.delims, r.literalLanguages) {
+			writer = r.literal
+		}
+		for i := range n.Lines().Len() {
+			line := n.Lines().At(i)
+			rawWriteAt(w, writer, line.Value(source), line.Start-line.Padding)
 		}
 	} else {
 		if _, err := w.WriteString("</code></pre>\n"); err != nil {
@@ -108,7 +251,7 @@ func (r *Renderer) renderCodeSpan(w util.BufWriter, source []byte, n gast.Node,
 			if _, err := w.WriteString("<code"); err != nil {
 				return gast.WalkStop, err
 			}
-			ghtml.RenderAttributes(w, n, ghtml.CodeAttributeFilter)
+			r.renderAttributes(w, n, ghtml.CodeAttributeFilter)
 			if err := w.WriteByte('>'); err != nil {
 				return gast.WalkStop, err
 			}
@@ -117,14 +260,15 @@ func (r *Renderer) renderCodeSpan(w util.BufWriter, source []byte, n gast.Node,
 				return gast.WalkStop, err
 			}
 		}
+		writer := r.writer(ast.ContextCodeSpan)
 		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
 			segment := c.(*gast.Text).Segment
 			value := segment.Value(source)
 			if bytes.HasSuffix(value, []byte("\n")) {
-				r.Writer.RawWrite(w, value[:len(value)-1])
-				r.Writer.RawWrite(w, []byte(" "))
+				rawWriteAt(w, writer, value[:len(value)-1], segment.Start)
+				writer.RawWrite(w, []byte(" "))
 			} else {
-				r.Writer.RawWrite(w, value)
+				rawWriteAt(w, writer, value, segment.Start)
 			}
 		}
 		return gast.WalkSkipChildren, nil
@@ -144,15 +288,19 @@ func (r *Renderer) renderImage(w util.BufWriter, source []byte, node gast.Node,
 	if _, err := w.WriteString("<img"); err != nil {
 		return gast.WalkStop, err
 	}
-	if err := r.writeAttribute(w, "src", n.Destination); err != nil {
+	values := ast.ValueSegmentsOf(n)
+	if err := r.writeAttribute(w, "src", n.Destination, values.Offset(n, ast.FieldDestination)); err != nil {
 		return gast.WalkStop, err
 	}
-	if err := r.writeAttribute(w, "alt", r.extractTextContent(n, source)); err != nil {
+	if err := r.writeAlt(w, source, n); err != nil {
 		return gast.WalkStop, err
 	}
-	if err := r.writeAttribute(w, "title", n.Title); err != nil {
+	if err := r.writeAttribute(w, "title", ast.LinkTitle(n, n.Title), values.Offset(n, ast.FieldTitle)); err != nil {
 		return gast.WalkStop, err
 	}
+	if n.Attributes() != nil {
+		r.renderAttributes(w, n, ghtml.ImageAttributeFilter)
+	}
 	if r.XHTML {
 		if _, err := w.WriteString(" />"); err != nil {
 			return gast.WalkStop, err
@@ -171,12 +319,16 @@ func (r *Renderer) renderLink(w util.BufWriter, source []byte, node gast.Node, e
 		if _, err := w.WriteString("<a"); err != nil {
 			return gast.WalkStop, err
 		}
-		if err := r.writeAttribute(w, "href", n.Destination); err != nil {
+		values := ast.ValueSegmentsOf(n)
+		if err := r.writeAttribute(w, "href", n.Destination, values.Offset(n, ast.FieldDestination)); err != nil {
 			return gast.WalkStop, err
 		}
-		if err := r.writeAttribute(w, "title", n.Title); err != nil {
+		if err := r.writeAttribute(w, "title", ast.LinkTitle(n, n.Title), values.Offset(n, ast.FieldTitle)); err != nil {
 			return gast.WalkStop, err
 		}
+		if n.Attributes() != nil {
+			r.renderAttributes(w, n, ghtml.LinkAttributeFilter)
+		}
 		if err := w.WriteByte('>'); err != nil {
 			return gast.WalkStop, err
 		}
@@ -203,8 +355,38 @@ func (r *Renderer) extractTextContent(n gast.Node, source []byte) []byte {
 	return buf.Bytes()
 }
 
-// writeAttribute writes an HTML attribute with template preservation
-func (r *Renderer) writeAttribute(w util.BufWriter, name string, value []byte) error {
+// writeAlt writes the alt attribute of an image from its text content. The
+// children are written one by one rather than through extractTextContent so
+// that the actions among them keep their source positions.
+func (r *Renderer) writeAlt(w util.BufWriter, source []byte, n *gast.Image) error {
+	if r.extractTextContent(n, source) == nil {
+		return nil
+	}
+	if _, err := w.WriteString(` alt="`); err != nil {
+		return err
+	}
+	err := gast.Walk(n, func(node gast.Node, entering bool) (gast.WalkStatus, error) {
+		if entering {
+			if text, ok := node.(*gast.Text); ok {
+				rawWriteAt(w, r.Writer, text.Segment.Value(source), text.Segment.Start)
+			} else if td, ok := node.(*ast.TemplateAction); ok {
+				if !r.policy.Allows(ast.ContextAlt) {
+					return gast.WalkContinue, writeLiteralAction(w, td.Content, r.delims, td.Segment.Start)
+				}
+				return gast.WalkContinue, writeActionFrom(w, td.Content, td.Segment)
+			}
+		}
+		return gast.WalkContinue, nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.WriteByte('"')
+}
+
+// writeAttribute writes an HTML attribute with template preservation. offset
+// is where value starts in the source, or -1 if that is unknown.
+func (r *Renderer) writeAttribute(w util.BufWriter, name string, value []byte, offset int) error {
 	if value == nil {
 		return nil
 	}
@@ -213,11 +395,11 @@ func (r *Renderer) writeAttribute(w util.BufWriter, name string, value []byte) e
 	}
 
 	// Determine if this is a URL attribute that needs URL escaping
-	isURLAttribute := name == "href" || name == "src"
+	isURLAttribute := isURLAttribute([]byte(name))
 	
-	if hasAction(value) {
+	if r.hasAction(value) {
 		// For values with templates, we need to handle URL vs HTML escaping properly
-		r.writeAttributeWithTemplates(w, value, isURLAttribute)
+		r.writeAttributeWithTemplates(w, value, offset, isURLAttribute, attributeContext(name))
 	} else {
 		// For values without templates, use goldmark's standard processing
 		if isURLAttribute {
@@ -239,9 +421,33 @@ func (r *Renderer) writeAttribute(w util.BufWriter, name string, value []byte) e
 	return nil
 }
 
-// writeAttributeWithTemplates handles attribute values containing template actions
-func (r *Renderer) writeAttributeWithTemplates(w util.BufWriter, value []byte, isURLAttribute bool) error {
-	actionPattern := []byte("{{")
+// urlAttributes are the attributes whose values are URLs, which are
+// URL-escaped like link destinations.
+var urlAttributes = util.NewBytesFilterString("href,src,cite,action,formaction,poster,longdesc,usemap")
+
+// isURLAttribute reports whether the value of the attribute name is a URL.
+func isURLAttribute(name []byte) bool {
+	return urlAttributes.Contains(name)
+}
+
+// attributeContext returns the context of the actions in the value of the
+// attribute name.
+func attributeContext(name string) ast.ActionContext {
+	switch name {
+	case "href":
+		return ast.ContextHref
+	case "src":
+		return ast.ContextSrc
+	case "title":
+		return ast.ContextTitle
+	}
+	return ast.ContextAttribute
+}
+
+// writeAttributeWithTemplates handles attribute values containing template
+// actions. offset is where value starts in the source, or -1.
+func (r *Renderer) writeAttributeWithTemplates(w util.BufWriter, value []byte, offset int, isURLAttribute bool, context ast.ActionContext) error {
+	actionPattern := r.delims.Left
 	n := 0
 	i := 0
 
@@ -269,13 +475,19 @@ func (r *Renderer) writeAttributeWithTemplates(w util.BufWriter, value []byte, i
 		}
 
 		// Find and write the complete template action verbatim
-		end := tutil.FindActionEnd(value, i)
+		end := r.delims.FindActionEnd(value, i)
 		if end <= 0 {
 			i++
 			continue
 		}
 
-		if _, err := w.Write(value[i:end]); err != nil {
+		var err error
+		if r.policy.Allows(context) {
+			err = writeAction(w, value[i:end], offsetAt(offset, i))
+		} else {
+			err = writeLiteralAction(w, value[i:end], r.delims, offsetAt(offset, i))
+		}
+		if err != nil {
 			return err
 		}
 		n = end
@@ -308,6 +520,11 @@ func (r *Renderer) renderAutoLink(w util.BufWriter, source []byte, node gast.Nod
 	n := node.(*gast.AutoLink)
 	url := n.URL(source)
 	label := n.Label(source)
+	labelOffset := ast.ValueSegmentsOf(n).Offset(n, ast.FieldLabel)
+	urlOffset := -1
+	if bytes.Equal(url, label) {
+		urlOffset = labelOffset
+	}
 
 	if n.AutoLinkType == gast.AutoLinkEmail {
 		if _, err := w.WriteString(`<a href="mailto:`); err != nil {
@@ -320,7 +537,10 @@ func (r *Renderer) renderAutoLink(w util.BufWriter, source []byte, node gast.Nod
 	}
 
 	// Use raw write to preserve templates in URLs
-	if hasAction(url) {
+	if r.hasAction(url) && !r.policy.Allows(ast.ContextHref) {
+		rawWriteAt(w, r.literal, url, urlOffset)
+	} else if r.hasAction(url) {
+		mapActions(w, url, r.delims, urlOffset)
 		if _, err := w.Write(url); err != nil {
 			return gast.WalkStop, err
 		}
@@ -331,27 +551,89 @@ func (r *Renderer) renderAutoLink(w util.BufWriter, source []byte, node gast.Nod
 	if _, err := w.WriteString(`">`); err != nil {
 		return gast.WalkStop, err
 	}
-	r.Writer.RawWrite(w, label)
+	rawWriteAt(w, r.writer(ast.ContextText), label, labelOffset)
 	if _, err := w.WriteString(`</a>`); err != nil {
 		return gast.WalkStop, err
 	}
 	return gast.WalkSkipChildren, nil
 }
 
-func (r *Renderer) writeLines(w util.BufWriter, source []byte, n gast.Node) error {
+func (r *Renderer) writeLines(w util.BufWriter, source []byte, n gast.Node, context ast.ActionContext) error {
+	writer := r.writer(context)
 	l := n.Lines().Len()
 	for i := range l {
 		line := n.Lines().At(i)
-		r.Writer.RawWrite(w, line.Value(source))
+		rawWriteAt(w, writer, line.Value(source), line.Start-line.Padding)
 	}
 	return nil
 }
 
+// renderHTMLBlock renders an HTML block like goldmark does, applying the
+// action policy for raw HTML.
+func (r *Renderer) renderHTMLBlock(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	n := node.(*gast.HTMLBlock)
+	var lines []text.Segment
+	if entering {
+		for i := 0; i < n.Lines().Len(); i++ {
+			lines = append(lines, n.Lines().At(i))
+		}
+	} else if n.HasClosure() {
+		lines = append(lines, n.ClosureLine)
+	} else {
+		return gast.WalkContinue, nil
+	}
+	if !r.Unsafe {
+		_, _ = w.WriteString("<!-- raw HTML omitted -->\n")
+		return gast.WalkContinue, nil
+	}
+	for i := range lines {
+		value := lines[i].Value(source)
+		start := lines[i].Start - lines[i].Padding
+		if r.policy.Allows(ast.ContextRawHTML) {
+			mapActions(w, value, r.delims, start)
+			ghtml.DefaultWriter.SecureWrite(w, value)
+		} else if err := writeRawActions(w, value, r.delims, true, start); err != nil {
+			return gast.WalkStop, err
+		}
+	}
+	return gast.WalkContinue, nil
+}
+
+// renderRawHTML renders inline raw HTML like goldmark does, applying the
+// action policy for raw HTML.
+func (r *Renderer) renderRawHTML(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	if !entering {
+		return gast.WalkSkipChildren, nil
+	}
+	if !r.Unsafe {
+		_, _ = w.WriteString("<!-- raw HTML omitted -->")
+		return gast.WalkSkipChildren, nil
+	}
+	n := node.(*gast.RawHTML)
+	literal := !r.policy.Allows(ast.ContextRawHTML)
+	for i := 0; i < n.Segments.Len(); i++ {
+		segment := n.Segments.At(i)
+		if err := writeRawActions(w, segment.Value(source), r.delims, literal, segment.Start); err != nil {
+			return gast.WalkStop, err
+		}
+	}
+	return gast.WalkSkipChildren, nil
+}
+
 // renderAttributes renders given node's attributes with template action preservation.
 // This copies goldmark's RenderAttributes logic but uses our template-aware attribute handling.
 func (r *Renderer) renderAttributes(w util.BufWriter, node gast.Node, filter util.BytesFilter) {
 	dataPrefix := []byte("data-")
+	values := ast.ValueSegmentsOf(node)
 	for _, attr := range node.Attributes() {
+		offset := values.Offset(node, ast.AttributeField(attr.Name))
+		if bytes.HasPrefix(attr.Name, r.delims.Left) {
+			// An action that stands for whole attributes goes where the
+			// attribute list is, for html/template to handle in the tag.
+			_ = w.WriteByte(' ')
+			_ = writeRawActions(w, attr.Name, r.delims, !r.policy.Allows(ast.ContextAttribute), offset)
+			continue
+		}
 		if filter != nil && !filter.Contains(attr.Name) {
 			if !bytes.HasPrefix(attr.Name, dataPrefix) {
 				continue
@@ -370,8 +652,11 @@ func (r *Renderer) renderAttributes(w util.BufWriter, node gast.Node, filter uti
 		}
 		
 		// Use our template-aware attribute value handling instead of goldmark's EscapeHTML
-		if hasAction(value) {
-			r.writeAttributeWithTemplates(w, value, false) // false = not a URL attribute
+		isURL := isURLAttribute(attr.Name)
+		if r.hasAction(value) {
+			r.writeAttributeWithTemplates(w, value, offset, isURL, ast.ContextAttribute)
+		} else if isURL {
+			_, _ = w.Write(util.EscapeHTML(util.URLEscape(value, true)))
 		} else {
 			// For non-template values, use goldmark's standard HTML escaping
 			_, _ = w.Write(util.EscapeHTML(value))
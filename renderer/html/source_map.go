@@ -0,0 +1,277 @@
+package html
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Mapping maps a range of the rendered HTML to the Markdown it came from.
+type Mapping struct {
+	// Output is the byte range in the rendered HTML.
+	Output text.Segment
+
+	// Source is the byte range in the Markdown source.
+	Source text.Segment
+}
+
+// SourceMap records where the actions of a rendered document came from in
+// the Markdown source. It is a util.BufWriter: pass it to Convert in place
+// of the output writer, and the renderers record a Mapping for every action
+// they preserve.
+//
+//	sm := html.NewSourceMap(&buf, source)
+//	err := md.Convert(source, sm)
+type SourceMap struct {
+	// Mappings lists the preserved actions in output order.
+	Mappings []Mapping
+
+	w      *bufio.Writer
+	source []byte
+	n      int
+	// lines holds the output offset at which each line after the first
+	// starts.
+	lines []int
+}
+
+// NewSourceMap returns a SourceMap that writes the rendered HTML of source
+// to w.
+func NewSourceMap(w io.Writer, source []byte) *SourceMap {
+	return &SourceMap{
+		w:      bufio.NewWriter(w),
+		source: source,
+	}
+}
+
+// Len returns the number of bytes written so far.
+func (m *SourceMap) Len() int {
+	return m.n
+}
+
+// Write implements io.Writer.
+func (m *SourceMap) Write(p []byte) (int, error) {
+	n, err := m.w.Write(p)
+	m.advance(p[:n])
+	return n, err
+}
+
+// WriteString implements io.StringWriter.
+func (m *SourceMap) WriteString(s string) (int, error) {
+	n, err := m.w.WriteString(s)
+	m.advance(util.StringToReadOnlyBytes(s[:n]))
+	return n, err
+}
+
+// WriteByte implements io.ByteWriter.
+func (m *SourceMap) WriteByte(c byte) error {
+	if err := m.w.WriteByte(c); err != nil {
+		return err
+	}
+	m.advance([]byte{c})
+	return nil
+}
+
+// WriteRune writes a single rune.
+func (m *SourceMap) WriteRune(r rune) (int, error) {
+	var buf [utf8.UTFMax]byte
+	return m.Write(utf8.AppendRune(buf[:0], r))
+}
+
+// Available returns how many bytes are unused in the buffer.
+func (m *SourceMap) Available() int {
+	return m.w.Available()
+}
+
+// Buffered returns the number of bytes that have been written into the
+// buffer.
+func (m *SourceMap) Buffered() int {
+	return m.w.Buffered()
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (m *SourceMap) Flush() error {
+	return m.w.Flush()
+}
+
+func (m *SourceMap) advance(p []byte) {
+	for i, c := range p {
+		if c == '\n' {
+			m.lines = append(m.lines, m.n+i+1)
+		}
+	}
+	m.n += len(p)
+}
+
+// record maps the next length bytes of output to src.
+func (m *SourceMap) record(length int, src text.Segment) {
+	m.Mappings = append(m.Mappings, Mapping{
+		Output: text.NewSegment(m.n, m.n+length),
+		Source: src,
+	})
+}
+
+// Lookup returns the Markdown range of the action that contains the output
+// offset.
+func (m *SourceMap) Lookup(offset int) (text.Segment, bool) {
+	i := sort.Search(len(m.Mappings), func(i int) bool {
+		return m.Mappings[i].Output.Stop > offset
+	})
+	if i == len(m.Mappings) || m.Mappings[i].Output.Start > offset {
+		return text.Segment{}, false
+	}
+	return m.Mappings[i].Source, true
+}
+
+// Position returns the 1-based Markdown line and column of the output
+// position given as a 1-based line and a 0-based byte column, the form
+// html/template uses in its errors. A column of -1 stands for the first
+// action on the line.
+func (m *SourceMap) Position(line, column int) (int, int, bool) {
+	if line < 1 || line > len(m.lines)+1 {
+		return 0, 0, false
+	}
+	start, stop := 0, m.n
+	if line > 1 {
+		start = m.lines[line-2]
+	}
+	if line <= len(m.lines) {
+		stop = m.lines[line-1]
+	}
+
+	if column < 0 {
+		for _, mapping := range m.Mappings {
+			if mapping.Output.Start >= start && mapping.Output.Start < stop {
+				l, c := tutil.LineColumn(m.source, mapping.Source.Start)
+				return l, c, true
+			}
+		}
+		return 0, 0, false
+	}
+
+	offset := start + column
+	i := sort.Search(len(m.Mappings), func(i int) bool {
+		return m.Mappings[i].Output.Stop > offset
+	})
+	if i == len(m.Mappings) || m.Mappings[i].Output.Start > offset {
+		return 0, 0, false
+	}
+	mapping := m.Mappings[i]
+	// Block-level actions lose their trim markers in the output, so the
+	// offset inside the action is only approximate; keep it inside the
+	// source range.
+	src := mapping.Source.Start + offset - mapping.Output.Start
+	if src >= mapping.Source.Stop {
+		src = mapping.Source.Stop - 1
+	}
+	l, c := tutil.LineColumn(m.source, src)
+	return l, c, true
+}
+
+var templateErrorPosition = regexp.MustCompile(`template: ([^:\s]+):(\d+)(?::(\d+))?:`)
+
+// RewriteError rewrites the positions that html/template parse and exec
+// errors report in the rendered HTML of the template called name, such as
+// "template: name:57:12:", to point at the Markdown source instead, as
+// "template: name:3:9:". The rendered HTML must have been parsed as that
+// template. Positions in other templates, such as a partial that failed
+// while name was executed, are left alone, as are positions that do not
+// fall on a preserved action. The returned error wraps err.
+func (m *SourceMap) RewriteError(err error, name string) error {
+	if err == nil {
+		return nil
+	}
+	msg := templateErrorPosition.ReplaceAllStringFunc(err.Error(), func(s string) string {
+		sub := templateErrorPosition.FindStringSubmatch(s)
+		if sub[1] != name {
+			return s
+		}
+		line, _ := strconv.Atoi(sub[2])
+		column := -1
+		if sub[3] != "" {
+			column, _ = strconv.Atoi(sub[3])
+		}
+		l, c, ok := m.Position(line, column)
+		if !ok {
+			return s
+		}
+		return "template: " + name + ":" + strconv.Itoa(l) + ":" + strconv.Itoa(c) + ":"
+	})
+	return &rewrittenError{msg: msg, err: err}
+}
+
+type rewrittenError struct {
+	msg string
+	err error
+}
+
+func (e *rewrittenError) Error() string {
+	return e.msg
+}
+
+func (e *rewrittenError) Unwrap() error {
+	return e.err
+}
+
+// writeAction writes action, which starts at offset start in the source or
+// at -1 if its position is unknown, verbatim. When w is a SourceMap and the
+// position is known, the action is recorded.
+func writeAction(w util.BufWriter, action []byte, start int) error {
+	if m, ok := w.(*SourceMap); ok && start >= 0 {
+		m.record(len(action), text.NewSegment(start, start+len(action)))
+	}
+	_, err := w.Write(action)
+	return err
+}
+
+// offsetAt returns the offset in the source of byte i of a value that starts
+// at offset start, or -1 if the position of the value is unknown.
+func offsetAt(start, i int) int {
+	if start < 0 {
+		return -1
+	}
+	return start + i
+}
+
+// writeActionFrom writes output for the action at segment src, recording it
+// when w is a SourceMap.
+func writeActionFrom(w util.BufWriter, output []byte, src text.Segment) error {
+	if m, ok := w.(*SourceMap); ok {
+		m.record(len(output), src)
+	}
+	_, err := w.Write(output)
+	return err
+}
+
+// mapActions records the actions in b, which starts at offset start in the
+// source or at -1 if its position is unknown, when w is a SourceMap, before
+// b is written verbatim.
+func mapActions(w util.BufWriter, b []byte, delims tutil.Delimiters, start int) {
+	m, ok := w.(*SourceMap)
+	if !ok || start < 0 {
+		return
+	}
+	for i := 0; i < len(b); i++ {
+		if !bytes.HasPrefix(b[i:], delims.Left) {
+			continue
+		}
+		end := delims.FindActionEnd(b, i)
+		if end < 0 {
+			continue
+		}
+		m.Mappings = append(m.Mappings, Mapping{
+			Output: text.NewSegment(m.n+i, m.n+end),
+			Source: text.NewSegment(start+i, start+end),
+		})
+		i = end - 1
+	}
+}
+
+var _ util.BufWriter = (*SourceMap)(nil)
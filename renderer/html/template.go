@@ -1,7 +1,10 @@
 package html
 
 import (
+	"bytes"
+
 	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
 	gast "github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/renderer"
 	ghtml "github.com/yuin/goldmark/renderer/html"
@@ -12,12 +15,15 @@ import (
 // output with no HTML/URL escaping
 type TemplateActionHTMLRenderer struct {
 	ghtml.Config
+	delims tutil.Delimiters
+	policy ast.ActionPolicy
 }
 
 // NewTemplateActionHTMLRenderer returns a new TemplateActionHTMLRenderer
 func NewTemplateActionHTMLRenderer(opts ...ghtml.Option) renderer.NodeRenderer {
 	r := &TemplateActionHTMLRenderer{
 		Config: ghtml.NewConfig(),
+		delims: tutil.DefaultDelimiters,
 	}
 	for _, opt := range opts {
 		opt.SetHTMLOption(&r.Config)
@@ -25,9 +31,24 @@ func NewTemplateActionHTMLRenderer(opts ...ghtml.Option) renderer.NodeRenderer {
 	return r
 }
 
+// SetOption implements renderer.SetOptioner.
+func (r *TemplateActionHTMLRenderer) SetOption(name renderer.OptionName, value interface{}) {
+	switch name {
+	case OptDelims:
+		r.delims = value.(tutil.Delimiters)
+	case OptPolicy:
+		r.policy = value.(ast.ActionPolicy)
+	default:
+		r.Config.SetOption(name, value)
+	}
+}
+
 // RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs
 func (r *TemplateActionHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindTemplateAction, r.render)
+	reg.Register(ast.KindTemplateBlock, r.renderBlock)
+	reg.Register(ast.KindTemplateActionBlock, r.renderActionBlock)
+	reg.Register(ast.KindTemplateEscape, r.renderEscape)
 }
 
 // render renders template actions as raw content (no HTML encoding)
@@ -37,7 +58,12 @@ func (r *TemplateActionHTMLRenderer) render(
 	if entering {
 		if node, ok := n.(*ast.TemplateAction); ok {
 			// Write the template action as-is (no HTML encoding)
-			_, err := w.Write(node.Content)
+			var err error
+			if r.policy.Allows(ast.ContextText) {
+				err = writeActionFrom(w, node.Content, node.Segment)
+			} else {
+				err = writeLiteralAction(w, node.Content, r.delims, node.Segment.Start)
+			}
 			if err != nil {
 				return gast.WalkStop, err
 			}
@@ -45,3 +71,126 @@ func (r *TemplateActionHTMLRenderer) render(
 	}
 	return gast.WalkContinue, nil
 }
+
+// renderEscape renders an escaped left delimiter as an action that prints
+// the delimiter, such as {{"{{"}}
+func (r *TemplateActionHTMLRenderer) renderEscape(
+	w util.BufWriter, source []byte, n gast.Node, entering bool,
+) (gast.WalkStatus, error) {
+	if entering {
+		if _, err := w.Write(literalAction(r.delims.Left, r.delims)); err != nil {
+			return gast.WalkStop, err
+		}
+	}
+	return gast.WalkContinue, nil
+}
+
+// renderBlock renders the opening and closing actions of a TemplateBlock on
+// their own lines, without paragraph wrappers
+func (r *TemplateActionHTMLRenderer) renderBlock(
+	w util.BufWriter, source []byte, n gast.Node, entering bool,
+) (gast.WalkStatus, error) {
+	node := n.(*ast.TemplateBlock)
+	action := node.Opener
+	if !entering {
+		action = node.Closer
+	}
+	if action == nil {
+		return gast.WalkContinue, nil
+	}
+	switch node.Opener.Action.Kind {
+	case ast.ActionDefine, ast.ActionBlock:
+		// Keep the body of a named template free of the line breaks
+		// around its actions. Only a block is also executed in place, so
+		// only its end is followed by the line break of the main flow; a
+		// define moved to the end of the document trims its own.
+		if !entering && node.Opener.Action.Kind == ast.ActionDefine && node.Parent().Kind() == gast.KindDocument {
+			if err := r.writeHoistedEnd(w, action); err != nil {
+				return gast.WalkStop, err
+			}
+			return gast.WalkContinue, nil
+		}
+		if err := r.writeBlockAction(w, action); err != nil {
+			return gast.WalkStop, err
+		}
+		if !entering && node.Opener.Action.Kind == ast.ActionBlock {
+			if err := w.WriteByte('\n'); err != nil {
+				return gast.WalkStop, err
+			}
+		}
+		return gast.WalkContinue, nil
+	}
+	if err := r.writeActionLine(w, action); err != nil {
+		return gast.WalkStop, err
+	}
+	return gast.WalkContinue, nil
+}
+
+// renderActionBlock renders an action-only line such as {{ else }}
+func (r *TemplateActionHTMLRenderer) renderActionBlock(
+	w util.BufWriter, source []byte, n gast.Node, entering bool,
+) (gast.WalkStatus, error) {
+	if entering {
+		if err := r.writeActionLine(w, n.(*ast.TemplateActionBlock).Action); err != nil {
+			return gast.WalkStop, err
+		}
+	}
+	return gast.WalkContinue, nil
+}
+
+// writeActionLine writes a block-level action on a line of its own. All of
+// the whitespace around it is generated by the renderer, so its trim markers
+// are dropped: the only thing they could remove is the line structure
+// between the neighbouring blocks.
+func (r *TemplateActionHTMLRenderer) writeActionLine(w util.BufWriter, action *ast.TemplateAction) error {
+	if err := r.writeBlockAction(w, action); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
+// writeHoistedEnd writes the end of a define that was moved to the end of
+// the document on a line of its own, so that the document still ends with a
+// line break. The end trims that line break, which would otherwise be
+// output by the main template.
+func (r *TemplateActionHTMLRenderer) writeHoistedEnd(w util.BufWriter, action *ast.TemplateAction) error {
+	if !r.policy.Allows(ast.ContextBlock) {
+		return r.writeActionLine(w, action)
+	}
+	content := stripTrimMarkers(action, r.delims)
+	end := bytes.TrimRight(content[:len(content)-len(r.delims.Right)], " \t\r\n")
+	end = append(append(end[:len(end):len(end)], " -"...), r.delims.Right...)
+	if err := writeActionFrom(w, end, action.Segment); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
+// writeBlockAction writes a block-level action without its trim markers, or
+// literally if the policy does not allow block-level actions.
+func (r *TemplateActionHTMLRenderer) writeBlockAction(w util.BufWriter, action *ast.TemplateAction) error {
+	if !r.policy.Allows(ast.ContextBlock) {
+		return writeLiteralAction(w, action.Content, r.delims, action.Segment.Start)
+	}
+	return writeActionFrom(w, stripTrimMarkers(action, r.delims), action.Segment)
+}
+
+// stripTrimMarkers returns the content of action without its {{- and -}}
+// trim markers
+func stripTrimMarkers(action *ast.TemplateAction, delims tutil.Delimiters) []byte {
+	a := action.Action
+	if !a.TrimLeft && !a.TrimRight {
+		return action.Content
+	}
+	body := action.Content[len(delims.Left) : len(action.Content)-len(delims.Right)]
+	if a.TrimLeft {
+		body = body[1:]
+	}
+	if a.TrimRight {
+		body = body[:len(body)-1]
+	}
+	content := make([]byte, 0, len(delims.Left)+len(body)+len(delims.Right))
+	content = append(content, delims.Left...)
+	content = append(content, body...)
+	return append(content, delims.Right...)
+}
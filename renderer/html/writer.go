@@ -8,42 +8,48 @@ import (
 	"github.com/yuin/goldmark/util"
 )
 
-// Template detection utilities
-var (
-	actionPattern = []byte("{{")
-)
-
-// hasAction checks if content contains template actions
-func hasAction(content []byte) bool {
-	return bytes.Contains(content, actionPattern)
-}
-
 // Writer is a custom HTML writer that preserves Go template actions
 // without HTML escaping them, while properly handling escaped template cases.
 type Writer struct {
 	fallback ghtml.Writer
+	delims   tutil.Delimiters
+	// literal renders actions as text instead of preserving them.
+	literal bool
 }
 
 // NewWriter creates a new Writer
 func NewWriter(opts ...ghtml.WriterOption) ghtml.Writer {
+	return NewWriterDelims(tutil.DefaultDelimiters, opts...)
+}
+
+// NewWriterDelims creates a new Writer that preserves actions enclosed in
+// the given delimiters
+func NewWriterDelims(delims tutil.Delimiters, opts ...ghtml.WriterOption) ghtml.Writer {
 	return &Writer{
 		fallback: ghtml.NewWriter(opts...),
+		delims:   delims,
+	}
+}
+
+// newLiteralWriter returns a Writer that renders actions so that
+// html/template prints them as text.
+func newLiteralWriter(delims tutil.Delimiters) ghtml.Writer {
+	return &Writer{
+		fallback: ghtml.NewWriter(),
+		delims:   delims,
+		literal:  true,
 	}
 }
 
 // Write writes content with normal processing (includes entity resolution and backslash unescaping)
 func (w *Writer) Write(writer util.BufWriter, source []byte) {
-	if hasAction(source) {
-		w.writeWithTemplateSupport(writer, source, true)
-	} else {
-		w.fallback.Write(writer, source)
-	}
+	w.writeAt(writer, source, -1)
 }
 
 // SecureWrite writes content with security filtering
 func (w *Writer) SecureWrite(writer util.BufWriter, source []byte) {
-	if hasAction(source) {
-		w.writeWithTemplateSupport(writer, source, false)
+	if w.delims.ContainsAction(source) {
+		w.writeWithTemplateSupport(writer, source, false, -1)
 	} else {
 		w.fallback.SecureWrite(writer, source)
 	}
@@ -51,21 +57,38 @@ func (w *Writer) SecureWrite(writer util.BufWriter, source []byte) {
 
 // RawWrite writes content while preserving Go template actions (HTML escaping only)
 func (w *Writer) RawWrite(writer util.BufWriter, source []byte) {
-	if hasAction(source) {
-		w.writeWithTemplateSupport(writer, source, false)
+	w.rawWriteAt(writer, source, -1)
+}
+
+// writeAt is Write for content that starts at offset in the Markdown
+// source, so that its actions are recorded when writer is a SourceMap.
+func (w *Writer) writeAt(writer util.BufWriter, source []byte, offset int) {
+	if w.delims.ContainsAction(source) {
+		w.writeWithTemplateSupport(writer, source, true, offset)
+	} else {
+		w.fallback.Write(writer, source)
+	}
+}
+
+// rawWriteAt is RawWrite for content that starts at offset in the Markdown
+// source, so that its actions are recorded when writer is a SourceMap.
+func (w *Writer) rawWriteAt(writer util.BufWriter, source []byte, offset int) {
+	if w.delims.ContainsAction(source) {
+		w.writeWithTemplateSupport(writer, source, false, offset)
 	} else {
 		w.fallback.RawWrite(writer, source)
 	}
 }
 
-// writeWithTemplateSupport handles content with template actions
-func (w *Writer) writeWithTemplateSupport(writer util.BufWriter, source []byte, processEntities bool) {
+// writeWithTemplateSupport handles content with template actions. offset is
+// where source starts in the Markdown source, or -1 if that is unknown.
+func (w *Writer) writeWithTemplateSupport(writer util.BufWriter, source []byte, processEntities bool, offset int) {
 	n := 0
 	i := 0
 
 	for i < len(source) {
 		// Skip non-template characters
-		if i >= len(source)-1 || !bytes.HasPrefix(source[i:], actionPattern) {
+		if i >= len(source)-1 || !bytes.HasPrefix(source[i:], w.delims.Left) {
 			i++
 			continue
 		}
@@ -81,13 +104,19 @@ func (w *Writer) writeWithTemplateSupport(writer util.BufWriter, source []byte,
 		}
 
 		// Find and write the complete template action verbatim
-		end := tutil.FindActionEnd(source, i)
+		end := w.delims.FindActionEnd(source, i)
 		if end <= 0 {
 			i++
 			continue
 		}
 
-		if _, err := writer.Write(source[i:end]); err != nil {
+		var err error
+		if w.literal {
+			err = writeLiteralAction(writer, source[i:end], w.delims, offsetAt(offset, i))
+		} else {
+			err = writeAction(writer, source[i:end], offsetAt(offset, i))
+		}
+		if err != nil {
 			return
 		}
 		n = end
@@ -105,3 +134,22 @@ func (w *Writer) writeWithTemplateSupport(writer util.BufWriter, source []byte,
 	}
 }
 
+
+// writeAt writes source, which starts at offset in the Markdown source, with
+// writer, so that its actions are recorded when w is a SourceMap.
+func writeAt(w util.BufWriter, writer ghtml.Writer, source []byte, offset int) {
+	if tw, ok := writer.(*Writer); ok {
+		tw.writeAt(w, source, offset)
+		return
+	}
+	writer.Write(w, source)
+}
+
+// rawWriteAt is writeAt for RawWrite.
+func rawWriteAt(w util.BufWriter, writer ghtml.Writer, source []byte, offset int) {
+	if tw, ok := writer.(*Writer); ok {
+		tw.rawWriteAt(w, source, offset)
+		return
+	}
+	writer.RawWrite(w, source)
+}
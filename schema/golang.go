@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+)
+
+// GoStruct returns Go type declarations, without a package clause, for data
+// of shape s. The root type is called name, and each nested object gets a
+// type of its own named after its path, such as PageUser for .User. Fields
+// carry json tags that match the names JSONSchema uses. Values ranged over
+// become slices, with element types suffixed by Item. A Map becomes a
+// map[string]string when every known key is used as a URL, and a
+// map[string]any otherwise. The keys of an Object that are not exported Go
+// identifiers, such as "my-key" in {{ index . "my-key" }} or .params, are
+// left out of the struct and listed in its doc comment, since no struct
+// field can provide them.
+func GoStruct(s *Shape, name string) ([]byte, error) {
+	g := &goGenerator{names: map[string]bool{}}
+	g.queue = append(g.queue, goType{name: g.name(name), shape: s})
+	for len(g.queue) > 0 {
+		t := g.queue[0]
+		g.queue = g.queue[1:]
+		g.decl(t)
+	}
+	return format.Source(g.buf.Bytes())
+}
+
+type goType struct {
+	name  string
+	shape *Shape
+}
+
+type goGenerator struct {
+	buf   bytes.Buffer
+	names map[string]bool
+	queue []goType
+}
+
+func (g *goGenerator) decl(t goType) {
+	if g.buf.Len() > 0 {
+		g.buf.WriteString("\n")
+	}
+	var fields bytes.Buffer
+	var keys []string
+	for _, field := range fieldNames(t.shape) {
+		if !exported(field) {
+			keys = append(keys, strconv.Quote(field))
+			continue
+		}
+		typ := g.typeOf(t.shape.Fields[field], t.name+field)
+		fmt.Fprintf(&fields, "\t%s %s `json:%s`\n", field, typ, strconv.Quote(field))
+	}
+	if len(keys) > 0 {
+		fmt.Fprintf(&g.buf, "// %s is also read by the keys %s, which cannot be struct fields.\n", t.name, strings.Join(keys, ", "))
+	}
+	fmt.Fprintf(&g.buf, "type %s struct {\n", t.name)
+	g.buf.Write(fields.Bytes())
+	g.buf.WriteString("}\n")
+}
+
+// typeOf returns the Go type of s, queueing a declaration named name for
+// an Object.
+func (g *goGenerator) typeOf(s *Shape, name string) string {
+	switch s.Kind {
+	case String:
+		return "string"
+	case Array:
+		return "[]" + g.typeOf(s.Elem, name+"Item")
+	case Map:
+		for _, f := range s.Fields {
+			if f.Kind != String {
+				return "map[string]any"
+			}
+		}
+		if len(s.Fields) == 0 {
+			return "map[string]any"
+		}
+		return "map[string]string"
+	case Object:
+		name = g.name(name)
+		g.queue = append(g.queue, goType{name: name, shape: s})
+		return name
+	}
+	return "any"
+}
+
+// name returns a type name based on name that has not been used yet.
+func (g *goGenerator) name(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.names[unique] = true
+	return unique
+}
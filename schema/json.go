@@ -0,0 +1,51 @@
+package schema
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// JSONSchemaDraft is the JSON Schema dialect JSONSchema generates.
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema for data of shape s. Every field the
+// template reads is required; Any values accept any JSON value, and the
+// known keys of a Map are listed without forbidding others.
+func JSONSchema(s *Shape) ([]byte, error) {
+	doc := jsonSchema(s)
+	doc["$schema"] = JSONSchemaDraft
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func jsonSchema(s *Shape) map[string]any {
+	switch s.Kind {
+	case String:
+		return map[string]any{"type": "string"}
+	case Array:
+		return map[string]any{"type": "array", "items": jsonSchema(s.Elem)}
+	case Object, Map:
+		doc := map[string]any{"type": "object"}
+		names := fieldNames(s)
+		if len(names) > 0 {
+			props := make(map[string]any, len(names))
+			for _, name := range names {
+				props[name] = jsonSchema(s.Fields[name])
+			}
+			doc["properties"] = props
+		}
+		if s.Kind == Object && len(names) > 0 {
+			doc["required"] = names
+		}
+		return doc
+	}
+	return map[string]any{}
+}
+
+func fieldNames(s *Shape) []string {
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,141 @@
+// Package schema infers the shape of the data a Markdown template reads from
+// its references, and describes it as a JSON Schema or as Go types.
+package schema
+
+import (
+	"go/token"
+	"strconv"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+)
+
+// Kind is the kind of a Shape.
+type Kind int
+
+const (
+	// Any is a value the template only prints or tests.
+	Any Kind = iota
+	// String is a value used where a URL is expected, in an href or a src.
+	String
+	// Map is a value read by key with index, or through a field name that
+	// a Go struct cannot have, such as .params.
+	Map
+	// Object is a value whose fields are read, such as .User in
+	// {{ .User.Name }}. An Object may also have keys read like a Map's.
+	Object
+	// Array is a value that is ranged over or indexed by number.
+	Array
+)
+
+var kindNames = [...]string{
+	Any:    "any",
+	String: "string",
+	Map:    "map",
+	Object: "object",
+	Array:  "array",
+}
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Shape is the inferred shape of a value.
+type Shape struct {
+	// Kind is the kind of the value.
+	Kind Kind
+
+	// Fields holds the fields of an Object and the known keys of a Map.
+	Fields map[string]*Shape
+
+	// Elem is the shape of the elements of an Array.
+	Elem *Shape
+}
+
+// Infer returns the shape of the data that refs read, as returned by
+// ast.References. The root is always an Object. Field paths become nested
+// objects, values that are ranged over become arrays, and values used in an
+// href or a src become strings. When a value is used in several ways, the
+// more structured use wins: an array over an object, an object over a map,
+// a map over a string. References whose path is unknown are ignored.
+func Infer(refs []ast.Reference) *Shape {
+	root := &Shape{Kind: Object}
+	for _, ref := range refs {
+		if ref.Path == nil || ref.Kind == ast.ReferenceFunction {
+			continue
+		}
+		s := root
+		for _, field := range ref.Path {
+			s = s.child(field)
+		}
+		for _, key := range ref.Keys {
+			s = s.key(key)
+		}
+		if ref.Ranged {
+			s.use(Array)
+			if s.Elem == nil {
+				s.Elem = &Shape{}
+			}
+		}
+		if ref.Context == ast.ContextHref || ref.Context == ast.ContextSrc {
+			s.use(String)
+		}
+	}
+	return root
+}
+
+// child returns the shape at field, turning s into an object, or into an
+// array for ast.ElemSegment.
+func (s *Shape) child(field string) *Shape {
+	if field == ast.ElemSegment {
+		s.use(Array)
+		if s.Elem == nil {
+			s.Elem = &Shape{}
+		}
+		return s.Elem
+	}
+	if exported(field) {
+		s.use(Object)
+	} else {
+		s.use(Map)
+	}
+	return s.field(field)
+}
+
+// key returns the shape at an index key: an element for a number, a map
+// entry otherwise.
+func (s *Shape) key(key string) *Shape {
+	if _, err := strconv.Atoi(key); err == nil {
+		return s.child(ast.ElemSegment)
+	}
+	s.use(Map)
+	return s.field(key)
+}
+
+func (s *Shape) field(name string) *Shape {
+	if s.Fields == nil {
+		s.Fields = map[string]*Shape{}
+	}
+	f := s.Fields[name]
+	if f == nil {
+		f = &Shape{}
+		s.Fields[name] = f
+	}
+	return f
+}
+
+// use records a use of s as kind, keeping the most structured one.
+func (s *Shape) use(kind Kind) {
+	if kind > s.Kind {
+		s.Kind = kind
+	}
+}
+
+// exported reports whether name can be the name of a struct field that
+// html/template reads.
+func exported(name string) bool {
+	return token.IsIdentifier(name) && token.IsExported(name)
+}
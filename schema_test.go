@@ -0,0 +1,184 @@
+package goldmarktemplate
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	"github.com/hermit-ink/goldmark-template/schema"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func inferShape(t *testing.T, input string) *schema.Shape {
+	t.Helper()
+	md := goldmark.New(goldmark.WithExtensions(New(ParserOptions(parser.WithAttribute()))))
+	source := []byte(input)
+	doc := md.Parser().Parse(text.NewReader(source))
+	return schema.Infer(ast.References(doc, source, tutil.DefaultDelimiters))
+}
+
+func TestGoStruct(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "nested fields",
+			input: "# {{ .Title }}\n\nBy {{ .User.Name }} ({{ $.User.Email }})",
+			expected: "type Page struct {\n" +
+				"\tTitle any      `json:\"Title\"`\n" +
+				"\tUser  PageUser `json:\"User\"`\n" +
+				"}\n\n" +
+				"type PageUser struct {\n" +
+				"\tEmail any `json:\"Email\"`\n" +
+				"\tName  any `json:\"Name\"`\n" +
+				"}\n",
+		},
+		{
+			name:  "ranged values become slices",
+			input: "{{ range .Posts }}\n- [{{ .Title }}]({{ .URL }})\n{{ end }}\n\n{{ range .Tags }}{{ . }}{{ end }}",
+			expected: "type Page struct {\n" +
+				"\tPosts []PagePostsItem `json:\"Posts\"`\n" +
+				"\tTags  []any           `json:\"Tags\"`\n" +
+				"}\n\n" +
+				"type PagePostsItem struct {\n" +
+				"\tTitle any    `json:\"Title\"`\n" +
+				"\tURL   string `json:\"URL\"`\n" +
+				"}\n",
+		},
+		{
+			name:  "href and src are strings",
+			input: "[a]({{ .Link }}) ![b]({{ .Image.Src }}) <{{ .Home }}>",
+			expected: "type Page struct {\n" +
+				"\tHome  string    `json:\"Home\"`\n" +
+				"\tImage PageImage `json:\"Image\"`\n" +
+				"\tLink  string    `json:\"Link\"`\n" +
+				"}\n\n" +
+				"type PageImage struct {\n" +
+				"\tSrc string `json:\"Src\"`\n" +
+				"}\n",
+		},
+		{
+			name:  "index keys and variables",
+			input: "{{ $s := .Site }}{{ index .Params \"lang\" }} {{ index .Items 0 }} [x]({{ $s.URL }})",
+			expected: "type Page struct {\n" +
+				"\tItems  []any          `json:\"Items\"`\n" +
+				"\tParams map[string]any `json:\"Params\"`\n" +
+				"\tSite   PageSite       `json:\"Site\"`\n" +
+				"}\n\n" +
+				"type PageSite struct {\n" +
+				"\tURL string `json:\"URL\"`\n" +
+				"}\n",
+		},
+		{
+			name:  "keys that cannot be fields",
+			input: "# {{ .Title }}\n\n{{ index . \"my-key\" }} {{ index . \"My Key\" }} {{ .params.x }} {{ .User.Name }} {{ index .User \"id\" }}",
+			expected: "// Page is also read by the keys \"My Key\", \"my-key\", \"params\", which cannot be struct fields.\n" +
+				"type Page struct {\n" +
+				"\tTitle any      `json:\"Title\"`\n" +
+				"\tUser  PageUser `json:\"User\"`\n" +
+				"}\n\n" +
+				"// PageUser is also read by the keys \"id\", which cannot be struct fields.\n" +
+				"type PageUser struct {\n" +
+				"\tName any `json:\"Name\"`\n" +
+				"}\n",
+		},
+		{
+			name:  "type names stay unique",
+			input: "{{ .A.B.C }} {{ .AB.C }}",
+			expected: "type Page struct {\n" +
+				"\tA  PageA  `json:\"A\"`\n" +
+				"\tAB PageAB `json:\"AB\"`\n" +
+				"}\n\n" +
+				"type PageA struct {\n" +
+				"\tB PageAB2 `json:\"B\"`\n" +
+				"}\n\n" +
+				"type PageAB struct {\n" +
+				"\tC any `json:\"C\"`\n" +
+				"}\n\n" +
+				"type PageAB2 struct {\n" +
+				"\tC any `json:\"C\"`\n" +
+				"}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.GoStruct(inferShape(t, tt.input), "Page")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("GoStruct mismatch\nExpected:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "objects, arrays and maps",
+			input: "{{ range .Posts }}\n- [{{ .Title }}]({{ .URL }})\n{{ end }}\n\n{{ index .Params \"lang\" }}",
+			expected: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "object",
+				"required": ["Params", "Posts"],
+				"properties": {
+					"Params": {"type": "object", "properties": {"lang": {}}},
+					"Posts": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"required": ["Title", "URL"],
+							"properties": {"Title": {}, "URL": {"type": "string"}}
+						}
+					}
+				}
+			}`,
+		},
+		{
+			name:  "keys and lowercase fields keep the root an object",
+			input: "# {{ .Title }}\n\n{{ index . \"my-key\" }} {{ .params.x }}",
+			expected: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "object",
+				"required": ["Title", "my-key", "params"],
+				"properties": {
+					"Title": {},
+					"my-key": {},
+					"params": {"type": "object", "properties": {"x": {}}}
+				}
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.JSONSchema(inferShape(t, tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotValue, expectedValue any
+			if err := json.Unmarshal(got, &gotValue); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.expected), &expectedValue); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotValue, expectedValue) {
+				t.Errorf("JSONSchema mismatch\nExpected:\n%s\nGot:\n%s", strings.TrimSpace(tt.expected), got)
+			}
+		})
+	}
+}
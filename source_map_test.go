@@ -0,0 +1,154 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+
+	thtml "github.com/hermit-ink/goldmark-template/renderer/html"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestSourceMapCoversActions(t *testing.T) {
+	input := strings.Join([]string{
+		"# {{ .Title }} {class=\"{{ .Class }}\"}",
+		"",
+		"{{ range .Items -}}",
+		"* [{{ .Name }}]({{ .URL }} \"{{ .Tip }}\") ![{{ .Alt }}]({{ .Src }})",
+		"{{- end }}",
+		"",
+		"<{{ .Base }}/page> `{{ .Code }}`",
+		"",
+		"```",
+		"{{ .Block }}",
+		"```",
+	}, "\n")
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(ParserOptions(parser.WithAttribute()))),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	source := []byte(input)
+	var buf bytes.Buffer
+	sm := thtml.NewSourceMap(&buf, source)
+	if err := md.Convert(source, sm); err != nil {
+		t.Fatalf("Failed to convert markdown: %v", err)
+	}
+
+	output := buf.Bytes()
+	var mapped []string
+	for _, m := range sm.Mappings {
+		out := string(m.Output.Value(output))
+		src := string(m.Source.Value(source))
+		if strings.Replace(strings.Replace(src, "{{- ", "{{ ", 1), " -}}", " }}", 1) != out {
+			t.Errorf("mapping %v -> %v: output %q does not match source %q", m.Output, m.Source, out, src)
+		}
+		mapped = append(mapped, out)
+	}
+
+	expected := []string{
+		"{{ .Class }}", "{{ .Title }}", "{{ range .Items }}", "{{ .URL }}", "{{ .Tip }}",
+		"{{ .Name }}", "{{ .Src }}", "{{ .Alt }}", "{{ end }}", "{{ .Base }}", "{{ .Base }}",
+		"{{ .Code }}", "{{ .Block }}",
+	}
+	if strings.Join(mapped, " ") != strings.Join(expected, " ") {
+		t.Errorf("Mapped actions mismatch\nExpected: %q\nGot:      %q", expected, mapped)
+	}
+}
+
+func TestSourceMapRewriteError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		data     any
+		expected string
+	}{
+		{
+			name:     "exec error",
+			input:    "# Title\n\nSome text\n\nHello {{ .User.Name }}!",
+			data:     map[string]any{"User": nil},
+			expected: "template: page.md:5:15: executing",
+		},
+		{
+			name:     "exec error in link destination",
+			input:    "Intro\n\n[x]({{ index .Links 3 }})",
+			data:     map[string]any{"Links": []string{}},
+			expected: "template: page.md:3:8: executing",
+		},
+		{
+			name:     "parse error",
+			input:    "Intro\n\nText {{ .A ( }}",
+			expected: "template: page.md:3:6: unclosed left paren",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := []byte(tt.input)
+			var buf bytes.Buffer
+			sm := thtml.NewSourceMap(&buf, source)
+			if err := md.Convert(source, sm); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+
+			tmpl, err := template.New("page.md").Parse(buf.String())
+			if err == nil {
+				err = tmpl.Execute(&bytes.Buffer{}, tt.data)
+			}
+			if err == nil {
+				t.Fatalf("expected the template to fail")
+			}
+
+			rewritten := sm.RewriteError(err, "page.md")
+			if !strings.Contains(rewritten.Error(), tt.expected) {
+				t.Errorf("expected %q in %q (original %q)", tt.expected, rewritten.Error(), err.Error())
+			}
+			if !errors.Is(rewritten, err) {
+				t.Errorf("rewritten error does not wrap the original")
+			}
+		})
+	}
+}
+
+func TestSourceMapRewriteErrorInSet(t *testing.T) {
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	convert := func(input string) (string, *thtml.SourceMap) {
+		var buf bytes.Buffer
+		sm := thtml.NewSourceMap(&buf, []byte(input))
+		if err := md.Convert([]byte(input), sm); err != nil {
+			t.Fatalf("Failed to convert markdown: %v", err)
+		}
+		return buf.String(), sm
+	}
+	// Both documents have an action at the same position in their HTML.
+	page, pageMap := convert("# Title\n\nText\n\nHello {{ .User }} {{ template \"partial.md\" . }}")
+	partial, partialMap := convert("Intro\n\nMore text\n\nHello {{ .User.Name }}!")
+
+	set := template.Must(template.New("page.md").Parse(page))
+	template.Must(set.New("partial.md").Parse(partial))
+	err := set.ExecuteTemplate(&bytes.Buffer{}, "page.md", map[string]any{"User": nil})
+	if err == nil {
+		t.Fatalf("expected the template to fail")
+	}
+
+	// The error is in the partial, so the map of the page leaves it alone.
+	if got := pageMap.RewriteError(err, "page.md").Error(); got != err.Error() {
+		t.Errorf("page map rewrote a partial position: %q (original %q)", got, err.Error())
+	}
+	expected := "template: partial.md:5:15: executing"
+	if got := partialMap.RewriteError(err, "partial.md").Error(); !strings.Contains(got, expected) {
+		t.Errorf("expected %q in %q", expected, got)
+	}
+}
@@ -0,0 +1,203 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	tparser "github.com/hermit-ink/goldmark-template/parser"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestTemplateBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "range around paragraphs",
+			input:    "{{ range .Items }}\nFirst paragraph\n\nSecond paragraph\n{{ end }}",
+			expected: "{{ range .Items }}\n<p>First paragraph</p>\n<p>Second paragraph</p>\n{{ end }}",
+		},
+		{
+			name:     "if with else",
+			input:    "{{ if .User }}\nHello {{ .User.Name }}\n{{ else }}\nPlease sign in\n{{ end }}",
+			expected: "{{ if .User }}\n<p>Hello {{ .User.Name }}</p>\n{{ else }}\n<p>Please sign in</p>\n{{ end }}",
+		},
+		{
+			name:     "else if chain",
+			input:    "{{ if .A }}\nA\n{{ else if .B }}\nB\n{{ end }}",
+			expected: "{{ if .A }}\n<p>A</p>\n{{ else if .B }}\n<p>B</p>\n{{ end }}",
+		},
+		{
+			name:     "nested blocks",
+			input:    "{{ range .Items }}\n{{ with .Detail }}\n# {{ .Title }}\n{{ end }}\n{{ end }}",
+			expected: "{{ range .Items }}\n{{ with .Detail }}\n<h1>{{ .Title }}</h1>\n{{ end }}\n{{ end }}",
+		},
+		{
+			name:     "block interrupts a paragraph",
+			input:    "Intro\n{{ if .Show }}\nShown\n{{ end }}\nOutro",
+			expected: "<p>Intro</p>\n{{ if .Show }}\n<p>Shown</p>\n{{ end }}\n<p>Outro</p>",
+		},
+		{
+			name:     "range around a list",
+			input:    "{{ range .Groups }}\n- {{ .A }}\n- {{ .B }}\n{{ end }}",
+			expected: "{{ range .Groups }}\n<ul>\n<li>{{ .A }}</li>\n<li>{{ .B }}</li>\n</ul>\n{{ end }}",
+		},
+		{
+			name:     "end inside a fenced code block belongs to the code",
+			input:    "{{ with .Example }}\n```\n{{ end }}\n```\n{{ end }}",
+			expected: "{{ with .Example }}\n<pre><code>{{ end }}\n</code></pre>\n{{ end }}",
+		},
+		{
+			name:     "template call on its own line",
+			input:    "Intro\n\n{{ template \"footer\" . }}\n\nOutro",
+			expected: "<p>Intro</p>\n{{ template \"footer\" . }}\n<p>Outro</p>",
+		},
+		{
+			name:     "template call continuing a paragraph stays inline",
+			input:    "Intro\n{{ template \"name\" . }}",
+			expected: "<p>Intro\n{{ template \"name\" . }}</p>",
+		},
+		{
+			name:     "define with markdown body",
+			input:    "{{ define \"intro\" }}\nHello *there*\n{{ end }}",
+			expected: "{{ define \"intro\" }}<p>Hello <em>there</em></p>\n{{ end -}}",
+		},
+		{
+			name:     "indented action line stays a code block",
+			input:    "    {{ if .X }}",
+			expected: "<pre><code>{{ if .X }}\n</code></pre>",
+		},
+		{
+			name:     "inline control flow stays inline",
+			input:    "{{ if .X }}yes{{ end }}",
+			expected: "<p>{{ if .X }}yes{{ end }}</p>",
+		},
+		{
+			name:     "pipeline alone on a line stays a paragraph",
+			input:    "{{ .Name }}",
+			expected: "<p>{{ .Name }}</p>",
+		},
+		{
+			name:     "unclosed block",
+			input:    "{{ if .X }}\ntext",
+			expected: "{{ if .X }}\n<p>text</p>",
+		},
+		{
+			name:     "stray end",
+			input:    "text\n{{ end }}",
+			expected: "<p>text</p>\n{{ end }}",
+		},
+		{
+			name:     "block inside blockquote",
+			input:    "> {{ if .X }}\n> quoted\n> {{ end }}",
+			expected: "<blockquote>\n{{ if .X }}\n<p>quoted</p>\n{{ end }}\n</blockquote>",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+			html.WithXHTML(),
+		),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := md.Convert([]byte(tt.input), &buf)
+			if err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+
+			got := strings.TrimSpace(buf.String())
+			expected := strings.TrimSpace(tt.expected)
+
+			if got != expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, expected, got)
+			}
+		})
+	}
+}
+
+func TestTemplateBlocksWithGFM(t *testing.T) {
+	input := "{{ range .Rows }}\n| A | B |\n|---|---|\n| {{ .A }} | {{ .B }} |\n{{ end }}"
+	expected := "{{ range .Rows }}\n<table>\n<thead>\n<tr>\n<th>A</th>\n<th>B</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>{{ .A }}</td>\n<td>{{ .B }}</td>\n</tr>\n</tbody>\n</table>\n{{ end }}"
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(), extension.GFM),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(input), &buf); err != nil {
+		t.Fatalf("Failed to convert markdown: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != expected {
+		t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+func TestTemplateBlockEndOutsideContainer(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "end after the block quote of its if",
+			input:    "> {{ if .X }}\n> hi\n{{ end }}",
+			expected: []string{"{{ end }}: end is outside the container of the if it closes"},
+		},
+		{
+			name:     "end after the list item of its range",
+			input:    "- {{ range .Items }}\n  item\n\n{{ end }}",
+			expected: []string{"{{ end }}: end is outside the container of the range it closes"},
+		},
+		{
+			name:     "end inside the block quote of its if",
+			input:    "> {{ if .X }}\n> hi\n> {{ end }}",
+			expected: nil,
+		},
+		{
+			name:     "end of an inline if",
+			input:    "Hello {{ if .X }}world\n{{ end }}",
+			expected: nil,
+		},
+		{
+			name:     "missing end at the end of the document",
+			input:    "> {{ if .X }}\n> hi",
+			expected: nil,
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := []byte(tt.input)
+			pc := parser.NewContext()
+			var buf bytes.Buffer
+			if err := md.Convert(source, &buf, parser.WithContext(pc)); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+
+			var got []string
+			for _, d := range tparser.Diagnostics(pc) {
+				got = append(got, string(d.Segment.Value(source))+": "+d.Message)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Diagnostics mismatch\nExpected: %q\nGot:      %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	tparser "github.com/hermit-ink/goldmark-template/parser"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestTrimMarkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "inline trim markers are preserved",
+			input:    "Hello\n{{- .Name -}}\n!",
+			expected: "<p>Hello\n{{- .Name -}}\n!</p>",
+		},
+		{
+			name:     "block-level trim markers do not glue blocks together",
+			input:    "Intro\n\n{{- if .Show -}}\nShown\n{{- end -}}\n\nOutro",
+			expected: "<p>Intro</p>\n{{ if .Show }}\n<p>Shown</p>\n{{ end }}\n<p>Outro</p>",
+		},
+		{
+			name:     "block-level else with trim markers",
+			input:    "{{ if .A }}\nA\n{{- else -}}\nB\n{{ end }}",
+			expected: "{{ if .A }}\n<p>A</p>\n{{ else }}\n<p>B</p>\n{{ end }}",
+		},
+		{
+			name:     "block-level trim markers around a fenced code block",
+			input:    "{{ with .Code -}}\n```\n  indented\n```\n{{- end }}",
+			expected: "{{ with .Code }}\n<pre><code>  indented\n</code></pre>\n{{ end }}",
+		},
+		{
+			name:     "trimmed inline comment is preserved",
+			input:    "a {{- /* note */ -}} b",
+			expected: "<p>a {{- /* note */ -}} b</p>",
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+			html.WithXHTML(),
+		),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := md.Convert([]byte(tt.input), &buf)
+			if err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+
+			got := strings.TrimSpace(buf.String())
+			expected := strings.TrimSpace(tt.expected)
+
+			if got != expected {
+				t.Errorf("Output mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, expected, got)
+			}
+		})
+	}
+}
+
+func TestTrimMarkersKeepPreformattedContent(t *testing.T) {
+	input := "{{- range .Items -}}\n```\n{{ . }}\n  line\n```\n{{- end -}}"
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(input), &buf); err != nil {
+		t.Fatalf("Failed to convert markdown: %v", err)
+	}
+
+	tmpl, err := template.New("page").Parse(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, map[string]any{"Items": []string{"a"}}); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+
+	expected := "\n<pre><code>a\n  line\n</code></pre>\n\n"
+	if out.String() != expected {
+		t.Errorf("Output mismatch\nExpected: %q\nGot:      %q", expected, out.String())
+	}
+}
+
+func TestTrimMarkerDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "trim left inside fenced code block",
+			input:    "```\nfoo\n{{- .Bar }}\n```",
+			expected: []string{"{{- .Bar }}"},
+		},
+		{
+			name:     "trim right at end of code line",
+			input:    "    {{ .Bar -}}\n    next",
+			expected: []string{"{{ .Bar -}}"},
+		},
+		{
+			name:     "trim inside code span",
+			input:    "`a {{- .B }}`",
+			expected: []string{"{{- .B }}"},
+		},
+		{
+			name:     "trim marker at the start of code content is harmless",
+			input:    "`{{- .B }} a`",
+			expected: nil,
+		},
+		{
+			name:     "trim markers outside code are not reported",
+			input:    "a {{- .B -}} c",
+			expected: nil,
+		},
+		{
+			name:     "code without trim markers",
+			input:    "```\nfoo\n{{ .Bar }}\n```",
+			expected: nil,
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := []byte(tt.input)
+			pc := parser.NewContext()
+			var buf bytes.Buffer
+			if err := md.Convert(source, &buf, parser.WithContext(pc)); err != nil {
+				t.Fatalf("Failed to convert markdown: %v", err)
+			}
+
+			var got []string
+			for _, d := range tparser.Diagnostics(pc) {
+				got = append(got, string(d.Segment.Value(source)))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Diagnostics mismatch\nExpected: %q\nGot:      %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -7,11 +7,18 @@ type ActionState struct {
 	inDoubleQuotes  bool
 	inSingleQuotes  bool
 	inBackticks     bool
+	delims          Delimiters
 }
 
 // NewActionState creates a new template action state tracker
 func NewActionState() *ActionState {
-	return &ActionState{}
+	return DefaultDelimiters.NewActionState()
+}
+
+// NewActionState creates a new template action state tracker for actions
+// enclosed in d
+func (d Delimiters) NewActionState() *ActionState {
+	return &ActionState{delims: d}
 }
 
 // ProcessChar processes a character and updates template action state
@@ -37,7 +44,7 @@ func (t *ActionState) ProcessChar(line []byte, i int) bool {
 	}
 
 	// Track template action boundaries
-	if !t.inAction && i < len(line)-1 && char == '{' && line[i+1] == '{' {
+	if !t.inAction && hasPrefixAt(line, i, t.delims.Left) {
 		t.inAction = true
 		t.templateDepth = 1
 		return false
@@ -55,13 +62,13 @@ func (t *ActionState) ProcessChar(line []byte, i int) bool {
 
 		// Check for template action end only when not inside quotes/backticks
 		if !t.inDoubleQuotes && !t.inSingleQuotes && !t.inBackticks &&
-		   i < len(line)-1 && char == '}' && line[i+1] == '}' {
+		   hasPrefixAt(line, i, t.delims.Right) {
 			t.templateDepth--
 			if t.templateDepth == 0 {
 				t.inAction = false
 			}
 		} else if !t.inDoubleQuotes && !t.inSingleQuotes && !t.inBackticks &&
-		          i < len(line)-1 && char == '{' && line[i+1] == '{' {
+		          hasPrefixAt(line, i, t.delims.Left) {
 			t.templateDepth++
 		}
 	}
@@ -77,20 +84,27 @@ func (t *ActionState) InAction() bool {
 // FindActionEnd finds the end of a template action starting from position startPos
 // Returns the position after the closing }} or -1 if not found
 func FindActionEnd(line []byte, startPos int) int {
-	if startPos+2 >= len(line) || line[startPos] != '{' || line[startPos+1] != '{' {
+	return DefaultDelimiters.FindActionEnd(line, startPos)
+}
+
+// FindActionEnd finds the end of a template action enclosed in d starting
+// from position startPos
+// Returns the position after the closing d.Right or -1 if not found
+func (d Delimiters) FindActionEnd(line []byte, startPos int) int {
+	if startPos+len(d.Left) >= len(line) || !hasPrefixAt(line, startPos, d.Left) {
 		return -1
 	}
 
-	tracker := NewActionState()
+	tracker := d.NewActionState()
 
 	tracker.ProcessChar(line, startPos)
 
-	for i := startPos + 1; i < len(line)-1; i++ {
+	for i := startPos + 1; i <= len(line)-len(d.Right); i++ {
 		tracker.ProcessChar(line, i)
 
 		if !tracker.InAction() {
-			if i < len(line)-1 && line[i] == '}' && line[i+1] == '}' {
-				return i + 2
+			if hasPrefixAt(line, i, d.Right) {
+				return i + len(d.Right)
 			}
 		}
 	}
@@ -338,4 +338,58 @@ func TestActionStateEdgeCases(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestFindActionEndCustomDelims(t *testing.T) {
+	tests := []struct {
+		name     string
+		delims   Delimiters
+		input    string
+		startPos int
+		expected int
+	}{
+		{
+			name:     "square brackets",
+			delims:   NewDelimiters("[[", "]]"),
+			input:    "[[ .Var ]]",
+			startPos: 0,
+			expected: 10,
+		},
+		{
+			name:     "closing delimiter inside quotes",
+			delims:   NewDelimiters("[[", "]]"),
+			input:    `[[ index .M "]]" ]] tail`,
+			startPos: 0,
+			expected: 19,
+		},
+		{
+			name:     "default braces are plain text",
+			delims:   NewDelimiters("[[", "]]"),
+			input:    "{{ .Var }}",
+			startPos: 0,
+			expected: -1,
+		},
+		{
+			name:     "asymmetric delimiters of different lengths",
+			delims:   NewDelimiters("<%=", "%>"),
+			input:    "a <%= .Var %> b",
+			startPos: 2,
+			expected: 13,
+		},
+		{
+			name:     "empty delimiters fall back to defaults",
+			delims:   NewDelimiters("", ""),
+			input:    "{{ .Var }}",
+			startPos: 0,
+			expected: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.delims.FindActionEnd([]byte(tt.input), tt.startPos)
+			if result != tt.expected {
+				t.Errorf("FindActionEnd(%q, %d): expected %d, got %d", tt.input, tt.startPos, tt.expected, result)
+			}
+		})
+	}
+}
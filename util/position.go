@@ -0,0 +1,20 @@
+package util
+
+import (
+	"bytes"
+)
+
+// LineColumn returns the 1-based line and column, in bytes, of offset in
+// source.
+func LineColumn(source []byte, offset int) (int, int) {
+	if offset > len(source) {
+		offset = len(source)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := source[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestLineColumn(t *testing.T) {
+	source := []byte("first\nsecond line\n\nfourth")
+	tests := []struct {
+		offset int
+		line   int
+		column int
+	}{
+		{offset: 0, line: 1, column: 1},
+		{offset: 4, line: 1, column: 5},
+		{offset: 5, line: 1, column: 6},
+		{offset: 6, line: 2, column: 1},
+		{offset: 13, line: 2, column: 8},
+		{offset: 18, line: 3, column: 1},
+		{offset: 19, line: 4, column: 1},
+		{offset: 100, line: 4, column: 7},
+	}
+
+	for _, tt := range tests {
+		line, column := LineColumn(source, tt.offset)
+		if line != tt.line || column != tt.column {
+			t.Errorf("LineColumn(%d): expected %d:%d, got %d:%d", tt.offset, tt.line, tt.column, line, column)
+		}
+	}
+}
@@ -4,7 +4,41 @@ import (
 	"bytes"
 )
 
+// Delimiters are the left and right delimiters that enclose a template
+// action, as set by text/template's Template.Delims.
+type Delimiters struct {
+	Left  []byte
+	Right []byte
+}
+
+// DefaultDelimiters are the standard {{ and }} action delimiters.
+var DefaultDelimiters = Delimiters{Left: []byte("{{"), Right: []byte("}}")}
+
+// NewDelimiters returns Delimiters for the given left and right delimiters.
+// Like Template.Delims, an empty delimiter stands for the default.
+func NewDelimiters(left, right string) Delimiters {
+	d := DefaultDelimiters
+	if left != "" {
+		d.Left = []byte(left)
+	}
+	if right != "" {
+		d.Right = []byte(right)
+	}
+	return d
+}
+
 // ContainsAction checks if the given content contains Go template actions
 func ContainsAction(content []byte) bool {
-	return bytes.Contains(content, []byte("{{"))
+	return DefaultDelimiters.ContainsAction(content)
+}
+
+// ContainsAction checks if the given content contains template actions
+// opened by d.Left
+func (d Delimiters) ContainsAction(content []byte) bool {
+	return bytes.Contains(content, d.Left)
+}
+
+// hasPrefixAt reports whether line[i:] starts with prefix
+func hasPrefixAt(line []byte, i int, prefix []byte) bool {
+	return i < len(line) && bytes.HasPrefix(line[i:], prefix)
 }
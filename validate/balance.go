@@ -0,0 +1,136 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// ErrMissingEnd is reported for a block action that is never closed.
+var ErrMissingEnd = errors.New("missing end")
+
+// ErrUnexpectedEnd is reported for an end action with no open block.
+var ErrUnexpectedEnd = errors.New("end without an open block")
+
+// openAction is a block action waiting for its end.
+type openAction struct {
+	site *ast.ActionSite
+	// final reports whether a bare else has been seen, after which only
+	// the end may follow.
+	final bool
+}
+
+// Balance returns a Check that matches the if, range, with, define and
+// block actions of the whole document against their else branches and
+// ends, in the order they appear in the generated HTML. It reports the
+// openers, branches and ends that do not pair up, such as an if in a
+// heading whose end is missing or an extra end in a link title.
+//
+// An end that pairs with a block-level opener from another container, such
+// as a block quote that ends before it, is reported too, as the HTML of the
+// block would not nest.
+//
+// Malformed actions still count by their keyword, so that a typo in an if
+// does not also report its end; Syntax reports the typo itself.
+func Balance(delims tutil.Delimiters) Check {
+	return func(doc gast.Node, source []byte) []*Error {
+		var errs []*Error
+		report := func(site *ast.ActionSite, err error) {
+			errs = append(errs, NewError(source, site.Offset, site.Content, err))
+		}
+
+		var stack []*openAction
+		_ = ast.WalkActions(doc, source, delims, func(site *ast.ActionSite) error {
+			if errors.Is(site.Err, ast.ErrUnclosedAction) {
+				return nil
+			}
+			var top *openAction
+			if len(stack) > 0 {
+				top = stack[len(stack)-1]
+			}
+
+			switch kind := site.Action.Kind; {
+			case kind == ast.ActionDefine && len(stack) > 0:
+				report(site, errors.New("define must be at the top level"))
+				stack = append(stack, &openAction{site: site})
+			case kind.OpensBlock():
+				stack = append(stack, &openAction{site: site})
+			case kind.IsBranch():
+				if top == nil {
+					report(site, fmt.Errorf("%s without an open block", kind.Keyword()))
+				} else if err := checkBranch(top, kind); err != nil {
+					report(site, err)
+				}
+			case kind == ast.ActionEnd:
+				if top == nil {
+					report(site, ErrUnexpectedEnd)
+				} else {
+					if cutOff(top.site, site) {
+						report(site, fmt.Errorf("end outside the container of its %s", top.site.Action.Kind.Keyword()))
+					}
+					stack = stack[:len(stack)-1]
+				}
+			case kind == ast.ActionBreak || kind == ast.ActionContinue:
+				if !inRange(stack) {
+					report(site, fmt.Errorf("%s outside range", kind.Keyword()))
+				}
+			}
+			return nil
+		})
+
+		for _, open := range stack {
+			report(open.site, ErrMissingEnd)
+		}
+		return errs
+	}
+}
+
+// checkBranch reports whether a branch of the given kind may follow the
+// actions seen so far in the open block, and records it.
+func checkBranch(open *openAction, kind ast.ActionKind) error {
+	opener := open.site.Action.Kind
+	if open.final {
+		return fmt.Errorf("%s after else", kind.Keyword())
+	}
+	switch {
+	case kind == ast.ActionElse && (opener == ast.ActionIf || opener == ast.ActionWith || opener == ast.ActionRange):
+		open.final = true
+		return nil
+	case kind == ast.ActionElseIf && opener == ast.ActionIf:
+		return nil
+	case kind == ast.ActionElseWith && opener == ast.ActionWith:
+		return nil
+	}
+	return fmt.Errorf("%s inside %s", kind.Keyword(), opener.Keyword())
+}
+
+// cutOff reports whether end closes the block-level action at opener from
+// outside of the block it opened, which the end of its container cut off.
+func cutOff(opener, end *ast.ActionSite) bool {
+	block, ok := opener.Node.(*ast.TemplateBlock)
+	if !ok || block.Closer != nil {
+		return false
+	}
+	for n := end.Node; n != nil; n = n.Parent() {
+		if n == gast.Node(block) {
+			return false
+		}
+	}
+	return true
+}
+
+func inRange(stack []*openAction) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].site.Action.Kind {
+		case ast.ActionRange:
+			return true
+		case ast.ActionDefine, ast.ActionBlock:
+			// A template body cannot break out of the range around it.
+			return false
+		}
+	}
+	return false
+}
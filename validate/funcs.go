@@ -0,0 +1,93 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// arity is the number of arguments a builtin function takes; max is -1 for
+// a variadic function.
+type arity struct {
+	min, max int
+}
+
+// builtins are the functions html/template predefines.
+var builtins = map[string]arity{
+	"and":      {1, -1},
+	"call":     {1, -1},
+	"eq":       {1, -1},
+	"ge":       {2, 2},
+	"gt":       {2, 2},
+	"html":     {0, -1},
+	"index":    {1, -1},
+	"js":       {0, -1},
+	"le":       {2, 2},
+	"len":      {1, 1},
+	"lt":       {2, 2},
+	"ne":       {2, 2},
+	"not":      {1, 1},
+	"or":       {1, -1},
+	"print":    {0, -1},
+	"printf":   {1, -1},
+	"println":  {0, -1},
+	"slice":    {1, -1},
+	"urlquery": {0, -1},
+}
+
+// Funcs returns a Check that looks up every function called by the actions
+// in the document, wherever they end up in the generated HTML, in funcs and
+// among the html/template builtins. It reports functions that are not
+// defined and calls with the wrong number of arguments, judged by the
+// signature of the function in funcs. As with Template.Funcs, a function in
+// funcs replaces a builtin of the same name.
+func Funcs(delims tutil.Delimiters, funcs map[string]any) Check {
+	return func(doc gast.Node, source []byte) []*Error {
+		var errs []*Error
+		type key struct {
+			offset int
+			msg    string
+		}
+		seen := map[key]bool{}
+		for _, ref := range ast.References(doc, source, delims) {
+			if ref.Kind != ast.ReferenceFunction {
+				continue
+			}
+			err := checkCall(funcs, ref.Name, ref.Args)
+			if err == nil {
+				continue
+			}
+			// An autolink reports its label as both destination and text.
+			if k := (key{ref.Offset, err.Error()}); !seen[k] {
+				seen[k] = true
+				errs = append(errs, NewError(source, ref.Offset, ref.Content, err))
+			}
+		}
+		return errs
+	}
+}
+
+// checkCall checks a call of the function name with args arguments.
+func checkCall(funcs map[string]any, name string, args int) error {
+	if fn, ok := funcs[name]; ok {
+		t := reflect.TypeOf(fn)
+		if t == nil || t.Kind() != reflect.Func {
+			return fmt.Errorf("value for %s not a function", name)
+		}
+		_, err := call(t, name, args)
+		return err
+	}
+	a, ok := builtins[name]
+	switch {
+	case !ok:
+		return fmt.Errorf("function %q not defined", name)
+	case a.max < 0 && args < a.min:
+		return fmt.Errorf("wrong number of args for %s: want at least %d got %d", name, a.min, args)
+	case a.max >= 0 && args != a.min:
+		return fmt.Errorf("wrong number of args for %s: want %d got %d", name, a.min, args)
+	}
+	return nil
+}
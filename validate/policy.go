@@ -0,0 +1,56 @@
+package validate
+
+import (
+	"fmt"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// Policy returns a Check that reports every action in a context the policy
+// denies, such as an action in raw HTML when raw HTML is
+// ast.PolicyDeny. The code of a fenced code block in one of
+// literalLanguages, or with a literal attribute, is shown as text and never
+// reported; see ast.IsLiteralFence.
+func Policy(delims tutil.Delimiters, policy ast.ActionPolicy, literalLanguages ...string) Check {
+	return func(doc gast.Node, source []byte) []*Error {
+		var errs []*Error
+		_ = ast.WalkActions(doc, source, delims, func(site *ast.ActionSite) error {
+			if policy.Rule(site.Context) != ast.PolicyDeny {
+				return nil
+			}
+			if n, ok := site.Node.(*gast.FencedCodeBlock); ok && site.Context == ast.ContextCodeBlock &&
+				ast.IsLiteralFence(n, source, delims, literalLanguages) {
+				return nil
+			}
+			// An autolink reports its label as both destination and text;
+			// either context being denied is reported once.
+			if n := len(errs); n > 0 && errs[n-1].Offset == site.Offset {
+				return nil
+			}
+			errs = append(errs, NewError(source, site.Offset, site.Content,
+				fmt.Errorf("actions are not allowed %s", where(site))))
+			return nil
+		})
+		return errs
+	}
+}
+
+// where describes the context of site for an error message.
+func where(site *ast.ActionSite) string {
+	switch site.Context {
+	case ast.ContextBlock:
+		return "on a line of their own"
+	case ast.ContextAttribute:
+		if site.Attribute == "" {
+			return "in attribute lists"
+		}
+		return "in the " + site.Attribute + " attribute"
+	case ast.ContextHref, ast.ContextSrc, ast.ContextTitle, ast.ContextAlt:
+		return "in the " + site.Context.String() + " attribute"
+	case ast.ContextCodeSpan, ast.ContextCodeBlock:
+		return "in " + site.Context.String() + "s"
+	}
+	return "in " + site.Context.String()
+}
@@ -0,0 +1,31 @@
+package validate
+
+import (
+	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// Syntax returns a Check that parses every action in the document, wherever
+// it ends up in the generated HTML, and reports those text/template would
+// reject, including left delimiters that are never closed.
+func Syntax(delims tutil.Delimiters) Check {
+	return func(doc gast.Node, source []byte) []*Error {
+		var errs []*Error
+		// An autolink reports its label as both destination and text.
+		type key struct {
+			offset  int
+			content string
+		}
+		seen := map[key]bool{}
+		_ = ast.WalkActions(doc, source, delims, func(site *ast.ActionSite) error {
+			k := key{site.Offset, string(site.Content)}
+			if site.Err != nil && !seen[k] {
+				seen[k] = true
+				errs = append(errs, NewError(source, site.Offset, site.Content, site.Err))
+			}
+			return nil
+		})
+		return errs
+	}
+}
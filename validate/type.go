@@ -0,0 +1,201 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/hermit-ink/goldmark-template/ast"
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+)
+
+var (
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	stringType = reflect.TypeOf("")
+)
+
+// Type returns a Check that resolves every field chain in the document
+// against typ, the type of the data passed to Execute. It follows what dot
+// and variables refer to through with, range and block actions, and
+// reports fields and methods typ does not have, methods called with the
+// wrong number of arguments, and values that cannot be ranged over or
+// indexed. Chains that go through an interface, and chains inside a
+// {{ define }}, cannot be resolved and are not checked.
+func Type(delims tutil.Delimiters, typ reflect.Type) Check {
+	return func(doc gast.Node, source []byte) []*Error {
+		var errs []*Error
+		type key struct {
+			offset int
+			msg    string
+		}
+		seen := map[key]bool{}
+		for _, ref := range ast.References(doc, source, delims) {
+			err := checkReference(typ, ref)
+			if err == nil {
+				continue
+			}
+			// An autolink reports its label as both destination and text.
+			if k := (key{ref.Offset, err.Error()}); !seen[k] {
+				seen[k] = true
+				errs = append(errs, NewError(source, ref.Offset, ref.Content, err))
+			}
+		}
+		return errs
+	}
+}
+
+// checkReference resolves ref against the data type typ. The part of the
+// path that comes from dot or a variable is reported where it was
+// introduced, so it is only followed here.
+func checkReference(typ reflect.Type, ref ast.Reference) error {
+	if ref.Path == nil || ref.Kind == ast.ReferenceFunction {
+		return nil
+	}
+	base := ref.Path[:len(ref.Path)-len(ref.Fields)]
+	t := typ
+	for _, seg := range base {
+		var err error
+		if t, err = step(t, seg, 0); t == nil || err != nil {
+			return nil
+		}
+	}
+	for i, field := range ref.Fields {
+		args := 0
+		if i == len(ref.Fields)-1 && len(ref.Keys) == 0 {
+			args = ref.Args
+		}
+		var err error
+		if t, err = step(t, field, args); t == nil || err != nil {
+			return err
+		}
+	}
+	for range ref.Keys {
+		var err error
+		if t, err = index(t); t == nil || err != nil {
+			return err
+		}
+	}
+	if ref.Ranged {
+		if _, err := step(t, ast.ElemSegment, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// step returns the type of the field or method name of t, or of the
+// elements of t for ast.ElemSegment. It returns a nil type when t is an
+// interface, whose dynamic type is only known at execution.
+func step(t reflect.Type, name string, args int) (reflect.Type, error) {
+	t = indirect(t)
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		return nil, nil
+	}
+	if name == ast.ElemSegment {
+		switch t.Kind() {
+		case reflect.Array, reflect.Slice, reflect.Map, reflect.Chan:
+			return t.Elem(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return t, nil
+		case reflect.Func:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("range can't iterate over %s", t)
+	}
+
+	if m, ok := method(t, name); ok {
+		return call(m, name, args)
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if f, ok := t.FieldByName(name); ok && f.IsExported() {
+			if args > 0 {
+				return nil, fmt.Errorf("%s has arguments but cannot be invoked as function", name)
+			}
+			return f.Type, nil
+		}
+	case reflect.Map:
+		if stringType.AssignableTo(t.Key()) {
+			if args > 0 {
+				return nil, fmt.Errorf("%s is not a method but has arguments", name)
+			}
+			return t.Elem(), nil
+		}
+	case reflect.Interface:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("can't evaluate field %s in type %s", name, t)
+}
+
+// index returns the type of the elements that index reads from t.
+func index(t reflect.Type) (reflect.Type, error) {
+	t = indirect(t)
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		return t.Elem(), nil
+	case reflect.String:
+		return reflect.TypeOf(byte(0)), nil
+	case reflect.Interface:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("can't index item of type %s", t)
+}
+
+// method looks name up among the methods of t, including those with a
+// pointer receiver, which text/template can call on addressable values.
+func method(t reflect.Type, name string) (reflect.Type, bool) {
+	if m, ok := t.MethodByName(name); ok {
+		if t.Kind() == reflect.Interface {
+			return m.Type, true
+		}
+		return dropReceiver(m.Type), true
+	}
+	if t.Kind() != reflect.Interface {
+		if m, ok := reflect.PointerTo(t).MethodByName(name); ok {
+			return dropReceiver(m.Type), true
+		}
+	}
+	return nil, false
+}
+
+// call checks a call of the method or function m with args arguments the
+// way text/template does, and returns the type of its result.
+func call(m reflect.Type, name string, args int) (reflect.Type, error) {
+	numIn := m.NumIn()
+	if m.IsVariadic() {
+		if args < numIn-1 {
+			return nil, fmt.Errorf("wrong number of args for %s: want at least %d got %d", name, numIn-1, args)
+		}
+	} else if args != numIn {
+		return nil, fmt.Errorf("wrong number of args for %s: want %d got %d", name, numIn, args)
+	}
+	switch {
+	case m.NumOut() == 1:
+	case m.NumOut() == 2 && m.Out(1) == errorType:
+	default:
+		return nil, fmt.Errorf("can't call method/function %q with %d results", name, m.NumOut())
+	}
+	return m.Out(0), nil
+}
+
+// dropReceiver returns the type of a method value of a method expression
+// type.
+func dropReceiver(m reflect.Type) reflect.Type {
+	in := make([]reflect.Type, m.NumIn()-1)
+	for i := range in {
+		in[i] = m.In(i + 1)
+	}
+	out := make([]reflect.Type, m.NumOut())
+	for i := range out {
+		out[i] = m.Out(i)
+	}
+	return reflect.FuncOf(in, out, m.IsVariadic())
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,121 @@
+// Package validate checks the template actions of a converted document
+// before its output is handed to html/template.
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tutil "github.com/hermit-ink/goldmark-template/util"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Error is a problem with a template action, located in the Markdown
+// source.
+type Error struct {
+	// Line and Column are the 1-based position of the action in the
+	// Markdown source. Column counts bytes.
+	Line   int
+	Column int
+
+	// Offset is the byte offset of the action in the Markdown source.
+	Offset int
+
+	// Action is the offending action, delimiters included.
+	Action string
+
+	// Err is the underlying problem.
+	Err error
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s: %v", e.Line, e.Column, e.Action, e.Err)
+}
+
+// Unwrap returns the underlying problem.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError returns an Error for the action at offset in source.
+func NewError(source []byte, offset int, action []byte, err error) *Error {
+	line, column := tutil.LineColumn(source, offset)
+	return &Error{
+		Line:   line,
+		Column: column,
+		Offset: offset,
+		Action: string(action),
+		Err:    err,
+	}
+}
+
+// Errors is the list of problems found in a document, in source order.
+type Errors []*Error
+
+// Error implements error.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the individual errors.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// A Check inspects a parsed document and returns the problems it finds.
+type Check func(doc gast.Node, source []byte) []*Error
+
+// Run runs the checks on doc and returns the problems they found as Errors,
+// or nil if there are none.
+func Run(doc gast.Node, source []byte, checks ...Check) error {
+	var errs Errors
+	for _, check := range checks {
+		errs = append(errs, check(doc, source)...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Offset < errs[j].Offset
+	})
+	return errs
+}
+
+type checkRenderer struct {
+	checks []Check
+}
+
+// NewRenderer returns a renderer.NodeRenderer that runs the checks before a
+// document is rendered and fails the conversion with their Errors. It must
+// be registered with a higher priority than the default HTML renderer so
+// that it renders documents in its place.
+func NewRenderer(checks ...Check) renderer.NodeRenderer {
+	return &checkRenderer{checks: checks}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *checkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(gast.KindDocument, r.renderDocument)
+}
+
+func (r *checkRenderer) renderDocument(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if !entering {
+		return gast.WalkContinue, nil
+	}
+	if err := Run(n, source, r.checks...); err != nil {
+		return gast.WalkStop, err
+	}
+	return gast.WalkContinue, nil
+}
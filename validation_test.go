@@ -0,0 +1,236 @@
+package goldmarktemplate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hermit-ink/goldmark-template/validate"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "valid document",
+			input:    "# {{ .Title }}\n\n[link]({{ .URL }} \"{{ .Tip }}\")\n\n{{ range .Items }}\n* {{ . }}\n{{ end }}",
+			expected: nil,
+		},
+		{
+			name:     "unclosed action in text",
+			input:    "Intro\n\nHello {{ .Title }",
+			expected: []string{"3:7: {{ .Title }: unclosed action"},
+		},
+		{
+			name:     "malformed inline action",
+			input:    "Hello {{ if }} world {{ end }}",
+			expected: []string{"1:7: {{ if }}: missing value for if"},
+		},
+		{
+			name:     "block-level action",
+			input:    "{{ with }}\ntext\n{{ end }}",
+			expected: []string{"1:1: {{ with }}: missing value for with"},
+		},
+		{
+			name:     "link destination and title",
+			input:    "See [x]({{ .URL }} \"{{ template }}\")",
+			expected: []string{"1:21: {{ template }}: unexpected \"}}\" in template clause"},
+		},
+		{
+			name:     "image source",
+			input:    "text\n![alt]({{ .A ( }})",
+			expected: []string{"2:8: {{ .A ( }}: unclosed left paren"},
+		},
+		{
+			name:     "heading attribute",
+			input:    "# Title {class=\"{{ .A ( }}\"}",
+			expected: []string{"1:17: {{ .A ( }}: unclosed left paren"},
+		},
+		{
+			name:     "unquoted attribute value",
+			input:    "# Title {data-x={{ .A ( }}}",
+			expected: []string{"1:17: {{ .A ( }}: unclosed left paren"},
+		},
+		{
+			name:     "block attribute list",
+			input:    "Text\n{: title=\"{{ .A ( }}\"}",
+			expected: []string{"2:11: {{ .A ( }}: unclosed left paren"},
+		},
+		{
+			name:     "fenced code block attribute",
+			input:    "```go {data-x=\"{{ .A ( }}\"}\nx\n```",
+			expected: []string{"1:16: {{ .A ( }}: unclosed left paren"},
+		},
+		{
+			name:     "reference definition title",
+			input:    "[x][r]\n\n[r]: /y \"{{ template }}\"",
+			expected: []string{"3:10: {{ template }}: unexpected \"}}\" in template clause"},
+		},
+		{
+			name:     "fenced code block",
+			input:    "```\nok {{ .A }}\n{{ .B\n```",
+			expected: []string{"3:1: {{ .B: unclosed action"},
+		},
+		{
+			name:     "code span",
+			input:    "`{{ range }}{{ end }}`",
+			expected: []string{"1:2: {{ range }}: missing value for range"},
+		},
+		{
+			name:     "autolink reported once",
+			input:    "<https://x.test/{{ .A ( }}>",
+			expected: []string{"1:17: {{ .A ( }}: unclosed left paren"},
+		},
+		{
+			name:  "several problems in source order",
+			input: "{{ .A ( }} and {{ .X }\n\n[a]({{ template }})",
+			expected: []string{
+				"1:1: {{ .A ( }}: unclosed left paren",
+				"1:16: {{ .X }: unclosed action",
+				"3:5: {{ template }}: unexpected \"}}\" in template clause",
+			},
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(
+			WithValidation(),
+			ParserOptions(parser.WithAttribute()),
+		)),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := md.Convert([]byte(tt.input), &buf)
+
+			var got []string
+			if err != nil {
+				var errs validate.Errors
+				if !errors.As(err, &errs) {
+					t.Fatalf("expected validate.Errors, got %T: %v", err, err)
+				}
+				for _, e := range errs {
+					got = append(got, e.Error())
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Errors mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidationIsOptIn(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(New()))
+	var buf bytes.Buffer
+	if err := md.Convert([]byte("Hello {{ .Title }"), &buf); err != nil {
+		t.Fatalf("expected no error without WithValidation, got %v", err)
+	}
+}
+
+func TestValidationBalance(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "balanced across contexts",
+			input:    "# {{ if .A }}Title\n\n[x]({{ .URL }} \"{{ end }}\")\n\n`{{ range .Items }}{{ . }}{{ end }}`",
+			expected: nil,
+		},
+		{
+			name:     "if in heading without end",
+			input:    "Intro\n\n## {{ if .Draft }}Draft\n\nBody",
+			expected: []string{"3:4: {{ if .Draft }}: missing end"},
+		},
+		{
+			name:     "extra end in link title",
+			input:    "[x](/y \"{{ end }}\")",
+			expected: []string{"1:9: {{ end }}: end without an open block"},
+		},
+		{
+			name:     "opener in image alt closed in attribute",
+			input:    "![{{ with .Img }}](/a.png)\n\n# H {class=\"{{ end }}\"}",
+			expected: nil,
+		},
+		{
+			name:     "else if inside range",
+			input:    "{{ range .Items }}\nitem\n{{ else if .X }}\nnone\n{{ end }}",
+			expected: []string{"3:1: {{ else if .X }}: else if inside range"},
+		},
+		{
+			name:     "else after else",
+			input:    "{{ if .A }}a{{ else }}b{{ else }}c{{ end }}",
+			expected: []string{"1:24: {{ else }}: else after else"},
+		},
+		{
+			name:     "else without an open block",
+			input:    "a {{ else }} b",
+			expected: []string{"1:3: {{ else }}: else without an open block"},
+		},
+		{
+			name:     "break outside range",
+			input:    "{{ if .A }}{{ break }}{{ end }}",
+			expected: []string{"1:12: {{ break }}: break outside range"},
+		},
+		{
+			name:     "nested define",
+			input:    "{{ if .A }}\n{{ define \"x\" }}\nbody\n{{ end }}\n{{ end }}",
+			expected: []string{"2:1: {{ define \"x\" }}: define must be at the top level"},
+		},
+		{
+			name:     "nested inline define",
+			input:    "{{ if .A }}\nText {{ define \"x\" }}body{{ end }}\n{{ end }}",
+			expected: []string{"2:6: {{ define \"x\" }}: define must be at the top level"},
+		},
+		{
+			name:     "end outside the block quote of its if",
+			input:    "> {{ if .X }}\n> hi\n{{ end }}",
+			expected: []string{"3:1: {{ end }}: end outside the container of its if"},
+		},
+		{
+			name:     "block-level opener closed inline",
+			input:    "{{ with .User }}\nHello {{ .Name }} {{ end }}",
+			expected: nil,
+		},
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(New(
+			WithValidation(),
+			ParserOptions(parser.WithAttribute()),
+		)),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := md.Convert([]byte(tt.input), &buf)
+
+			var got []string
+			if err != nil {
+				var errs validate.Errors
+				if !errors.As(err, &errs) {
+					t.Fatalf("expected validate.Errors, got %T: %v", err, err)
+				}
+				for _, e := range errs {
+					got = append(got, e.Error())
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Errors mismatch\nInput:    %q\nExpected: %q\nGot:      %q", tt.input, tt.expected, got)
+			}
+		})
+	}
+}